@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/contrib/bridges/otelslog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// accessLogger emits one record per HTTP request through the OTel log
+// pipeline, under its own instrumentation scope so backends can tell access
+// logs from application logs; nil when ACCESS_LOG_ENABLED is false
+var accessLogger *slog.Logger
+
+func initAccessLog() {
+	if !getEnvBool("ACCESS_LOG_ENABLED", true) {
+		log.Printf("Access logging disabled")
+		return
+	}
+	accessLogger = newLevelLogger(otelslog.NewHandler("go-service/accesslog"))
+}
+
+// logAccess records a completed request. The bridge takes the trace and span
+// IDs from ctx; trace_id is repeated as an attribute so it can be queried like
+// any other field. 5xx responses log at error and 4xx at warn, so LOG_LEVEL
+// can cut access logs down to failures.
+func logAccess(ctx context.Context, r *http.Request, route string, status int, responseBytes int64, elapsed time.Duration) {
+	if accessLogger == nil {
+		return
+	}
+	level := slog.LevelInfo
+	switch {
+	case status >= http.StatusInternalServerError:
+		level = slog.LevelError
+	case status >= http.StatusBadRequest:
+		level = slog.LevelWarn
+	}
+
+	attrs := []slog.Attr{
+		slog.String("http.request.method", r.Method),
+		slog.String("http.route", route),
+		slog.String("url.path", r.URL.Path),
+		slog.Int("http.response.status_code", status),
+		slog.Int64("http.response.body.size", responseBytes),
+		slog.Float64("duration_ms", float64(elapsed.Microseconds())/1000),
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		attrs = append(attrs, slog.String("trace_id", sc.TraceID().String()))
+	}
+	// A cancelled request is still worth a record
+	accessLogger.LogAttrs(context.WithoutCancel(ctx), level, "HTTP request completed", attrs...)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// adminAuth requires "Authorization: Bearer <ADMIN_TOKEN>" when ADMIN_TOKEN is set
+func adminAuth(next http.Handler) http.Handler {
+	token := os.Getenv("ADMIN_TOKEN")
+	if token == "" {
+		return next
+	}
+
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			writeError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireAdminToken guards APIs that change a live instance: they are only
+// available when ADMIN_TOKEN is set, and then require it as a bearer token
+func requireAdminToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := os.Getenv("ADMIN_TOKEN")
+		if token == "" {
+			writeError(w, http.StatusForbidden, "Set ADMIN_TOKEN to enable this endpoint")
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// adminTokenRequired applies requireAdminToken to a handler, for the admin
+// APIs on the app port, which anyone who can reach the service can call
+func adminTokenRequired(next http.Handler) http.Handler {
+	return requireAdminToken(next.ServeHTTP)
+}
+
+// unprotected leaves a route open to anyone who can reach its port
+func unprotected(next http.Handler) http.Handler {
+	return next
+}
+
+// registerOperationalRoutes registers the probe, metrics, and internal
+// endpoints on mux, wrapping the admin APIs (fault injection, config, flush,
+// and /internal/admin) in protect and /metrics in protectMetrics. Probes are
+// never wrapped, so the kubelet never needs the admin token.
+func registerOperationalRoutes(mux *http.ServeMux, protect, protectMetrics func(http.Handler) http.Handler) {
+	mux.HandleFunc("/healthz", tracingMiddleware(healthHandler))
+	mux.HandleFunc("/readyz", tracingMiddleware(readyzHandler))
+	mux.Handle("/admin/error-rate", protect(tracingMiddleware(errorRateHandler)))
+	mux.Handle("/internal/config", protect(tracingMiddleware(internalConfigHandler)))
+	mux.Handle("/internal/flush", protect(tracingMiddleware(flushHandler)))
+	mux.Handle("/internal/admin", protect(tracingMiddleware(requireAdminToken(internalAdminHandler))))
+
+	// Serve Prometheus exposition format when using the pull exporter
+	if slices.Contains(splitList(os.Getenv("OTEL_METRICS_EXPORTER")), "prometheus") {
+		mux.Handle("/metrics", protectMetrics(promhttp.Handler()))
+	}
+}
+
+// newAdminMux serves net/http/pprof (heap, profile, trace, goroutine, ...) and
+// the operational endpoints, all behind adminAuth except the probes
+func newAdminMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/pprof/", adminAuth(http.HandlerFunc(pprof.Index)))
+	mux.Handle("/debug/pprof/cmdline", adminAuth(http.HandlerFunc(pprof.Cmdline)))
+	mux.Handle("/debug/pprof/profile", adminAuth(http.HandlerFunc(pprof.Profile)))
+	mux.Handle("/debug/pprof/symbol", adminAuth(http.HandlerFunc(pprof.Symbol)))
+	mux.Handle("/debug/pprof/trace", adminAuth(http.HandlerFunc(pprof.Trace)))
+	registerOperationalRoutes(mux, adminAuth, adminAuth)
+	return mux
+}
+
+// adminListenAddr binds the admin port on all interfaces only when
+// ADMIN_TOKEN protects it; otherwise pprof and the admin APIs would be open
+// to anyone who can reach the host, so it listens on loopback
+func adminListenAddr(port string) string {
+	if os.Getenv("ADMIN_TOKEN") == "" {
+		return net.JoinHostPort("127.0.0.1", port)
+	}
+	return ":" + port
+}
+
+// startAdminServer serves the admin mux on addr in the background so
+// profiling, scrapes, and probes during load tests don't share the
+// application port and can be firewalled separately from it. It shares the
+// API server's read and idle timeouts but has no write timeout, since
+// /debug/pprof/profile and /debug/pprof/trace stream for as long as asked.
+func startAdminServer(addr string) *http.Server {
+	server := &http.Server{
+		Addr:    addr,
+		Handler: newAdminMux(),
+	}
+	setReadTimeouts(server)
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("Admin server stopped: %v", err)
+		}
+	}()
+
+	return server
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// roleNone is the enduser.role metric value for tokens without a role claim
+const roleNone = "_none"
+
+// authenticator validates HMAC-signed JWT bearer tokens
+type authenticator struct {
+	secret    []byte
+	issuer    string
+	audience  string
+	roleClaim string
+	required  bool
+
+	principals *boundedValues
+	requests   metric.Int64Counter
+	failures   metric.Int64Counter
+}
+
+var auth *authenticator
+
+// initAuth enables bearer-token validation when JWT_SECRET is set. Tokens
+// must be signed with HS256/384/512 using that secret and, if configured,
+// carry JWT_ISSUER and JWT_AUDIENCE. With AUTH_REQUIRED=false (the default)
+// requests without a token pass through anonymously; an invalid token is
+// always rejected.
+func initAuth() error {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return nil
+	}
+
+	a := &authenticator{
+		secret:     []byte(secret),
+		issuer:     os.Getenv("JWT_ISSUER"),
+		audience:   os.Getenv("JWT_AUDIENCE"),
+		roleClaim:  envOr("role", "JWT_ROLE_CLAIM"),
+		required:   getEnvBool("AUTH_REQUIRED", false),
+		principals: newBoundedValues(max(getEnvInt("AUTH_MAX_PRINCIPALS", 50), 1)),
+	}
+
+	var err error
+	a.requests, err = meter.Int64Counter(
+		"enduser.requests",
+		metric.WithDescription("The number of authenticated HTTP requests per principal"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return err
+	}
+
+	a.failures, err = meter.Int64Counter(
+		"auth.failures",
+		metric.WithDescription("The number of HTTP requests rejected because authentication failed"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return err
+	}
+
+	auth = a
+	log.Printf("JWT authentication enabled (required: %t)", a.required)
+	return nil
+}
+
+// authProtected reports whether path serves application traffic that
+// authentication applies to; probes, metrics, and admin routes are exempt
+func authProtected(path string) bool {
+	return strings.HasPrefix(path, "/api/") || path == "/graphql" || path == "/ws"
+}
+
+// principal is the authenticated caller
+type principal struct {
+	id   string
+	role string
+}
+
+// authenticate validates the request's bearer token. It returns a nil
+// principal and empty reason for anonymous requests that are allowed
+// through, and a reason (used as the auth.failure.reason attribute) when
+// the request must be rejected.
+func (a *authenticator) authenticate(r *http.Request) (*principal, string) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		if a.required {
+			return nil, "missing_token"
+		}
+		return nil, ""
+	}
+	scheme, raw, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || raw == "" {
+		return nil, "malformed_header"
+	}
+
+	options := []jwt.ParserOption{jwt.WithValidMethods([]string{"HS256", "HS384", "HS512"})}
+	if a.issuer != "" {
+		options = append(options, jwt.WithIssuer(a.issuer))
+	}
+	if a.audience != "" {
+		options = append(options, jwt.WithAudience(a.audience))
+	}
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(*jwt.Token) (any, error) {
+		return a.secret, nil
+	}, options...)
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return nil, "expired"
+	case errors.Is(err, jwt.ErrTokenNotValidYet):
+		return nil, "not_yet_valid"
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return nil, "invalid_signature"
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return nil, "malformed_token"
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer), errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return nil, "invalid_claims"
+	case err != nil:
+		return nil, "invalid_token"
+	}
+
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return nil, "missing_subject"
+	}
+	role, _ := claims[a.roleClaim].(string)
+	return &principal{id: subject, role: role}, ""
+}
+
+// authMiddleware validates bearer tokens when JWT_SECRET is set. On success
+// the server span gets enduser.id/enduser.role and the request is counted
+// against its principal; on failure the span records an auth.failed event
+// and error status and the client gets a 401.
+func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if auth == nil || !authProtected(r.URL.Path) {
+			next(w, r)
+			return
+		}
+
+		ctx := r.Context()
+		span := trace.SpanFromContext(ctx)
+		user, reason := auth.authenticate(r)
+		if reason != "" {
+			span.AddEvent("auth.failed", trace.WithAttributes(
+				attribute.String("auth.failure.reason", reason),
+			))
+			setErrorType(ctx, span, errorTypeUnauthorized)
+			auth.failures.Add(ctx, 1, metric.WithAttributes(
+				attribute.String("auth.failure.reason", reason),
+				attribute.String("http.route", httpRoute(r)),
+			))
+
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			writeError(w, http.StatusUnauthorized, "Unauthorized: "+reason)
+			return
+		}
+		if user == nil {
+			span.SetAttributes(attribute.Bool("enduser.anonymous", true))
+			next(w, r)
+			return
+		}
+
+		span.SetAttributes(semconv.EnduserID(user.id))
+		role := roleNone
+		if user.role != "" {
+			span.SetAttributes(semconv.EnduserRole(user.role))
+			role = user.role
+		}
+		auth.requests.Add(ctx, 1, metric.WithAttributes(
+			semconv.EnduserID(auth.principals.value(user.id)),
+			semconv.EnduserRole(role),
+		))
+		next(w, r.WithContext(context.WithValue(ctx, enduserKey{}, user.id)))
+	}
+}
+
+type enduserKey struct{}
+
+// enduserID returns the authenticated principal authMiddleware stored on ctx,
+// or "" for anonymous requests
+func enduserID(ctx context.Context) string {
+	id, _ := ctx.Value(enduserKey{}).(string)
+	return id
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testJWTSecret = "test-secret"
+
+// signToken returns claims as an HS256 token signed with secret
+func signToken(t *testing.T, secret string, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	now := time.Now()
+	valid := jwt.MapClaims{"sub": "alice", "role": "admin", "iss": "issuer", "aud": "go-service", "exp": now.Add(time.Hour).Unix()}
+	claims := func(changes jwt.MapClaims) jwt.MapClaims {
+		merged := jwt.MapClaims{}
+		for k, v := range valid {
+			merged[k] = v
+		}
+		for k, v := range changes {
+			if v == nil {
+				delete(merged, k)
+			} else {
+				merged[k] = v
+			}
+		}
+		return merged
+	}
+	bearer := func(secret string, c jwt.MapClaims) string {
+		return "Bearer " + signToken(t, secret, c)
+	}
+
+	tests := []struct {
+		name          string
+		required      bool
+		path          string
+		authorization string
+		wantStatus    int
+		wantReason    string
+		wantUser      string
+	}{
+		{"valid token", false, "/api/orders", bearer(testJWTSecret, valid), http.StatusOK, "", "alice"},
+		{"lowercase scheme", false, "/api/orders", "bearer " + signToken(t, testJWTSecret, valid), http.StatusOK, "", "alice"},
+		{"anonymous allowed", false, "/api/orders", "", http.StatusOK, "", ""},
+		{"anonymous rejected when required", true, "/api/orders", "", http.StatusUnauthorized, "missing_token", ""},
+		{"exempt path", true, "/healthz", "", http.StatusOK, "", ""},
+		{"invalid token on exempt path", false, "/metrics", "Bearer nonsense", http.StatusOK, "", ""},
+		{"basic auth", false, "/api/orders", "Basic YWxpY2U6cHc=", http.StatusUnauthorized, "malformed_header", ""},
+		{"empty bearer", false, "/api/orders", "Bearer ", http.StatusUnauthorized, "malformed_header", ""},
+		{"garbage token", false, "/graphql", "Bearer nonsense", http.StatusUnauthorized, "malformed_token", ""},
+		{"wrong secret", false, "/api/orders", bearer("other-secret", valid), http.StatusUnauthorized, "invalid_signature", ""},
+		{"expired", false, "/api/orders", bearer(testJWTSecret, claims(jwt.MapClaims{"exp": now.Add(-time.Hour).Unix()})), http.StatusUnauthorized, "expired", ""},
+		{"not yet valid", false, "/api/orders", bearer(testJWTSecret, claims(jwt.MapClaims{"nbf": now.Add(time.Hour).Unix()})), http.StatusUnauthorized, "not_yet_valid", ""},
+		{"wrong issuer", false, "/api/orders", bearer(testJWTSecret, claims(jwt.MapClaims{"iss": "someone-else"})), http.StatusUnauthorized, "invalid_claims", ""},
+		{"wrong audience", false, "/ws", bearer(testJWTSecret, claims(jwt.MapClaims{"aud": "other-service"})), http.StatusUnauthorized, "invalid_claims", ""},
+		{"missing subject", false, "/api/orders", bearer(testJWTSecret, claims(jwt.MapClaims{"sub": nil})), http.StatusUnauthorized, "missing_subject", ""},
+		{"unsigned token", false, "/api/orders", "Bearer " + unsignedToken(t, valid), http.StatusUnauthorized, "invalid_signature", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JWT_SECRET", testJWTSecret)
+			t.Setenv("JWT_ISSUER", "issuer")
+			t.Setenv("JWT_AUDIENCE", "go-service")
+			t.Setenv("JWT_ROLE_CLAIM", "")
+			t.Setenv("AUTH_REQUIRED", strconv.FormatBool(tt.required))
+			saved := auth
+			t.Cleanup(func() { auth = saved })
+			if err := initAuth(); err != nil {
+				t.Fatal(err)
+			}
+
+			called, user := false, ""
+			handler := authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+				called, user = true, enduserID(r.Context())
+			})
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus == http.StatusUnauthorized {
+				if called {
+					t.Error("rejected request reached the handler")
+				}
+				if !strings.Contains(rec.Body.String(), "Unauthorized: "+tt.wantReason) {
+					t.Errorf("body = %s, want reason %q", rec.Body, tt.wantReason)
+				}
+				if rec.Header().Get("WWW-Authenticate") == "" {
+					t.Error("401 without WWW-Authenticate")
+				}
+				return
+			}
+			if !called {
+				t.Fatal("handler was not called")
+			}
+			if user != tt.wantUser {
+				t.Errorf("enduserID = %q, want %q", user, tt.wantUser)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareDisabled(t *testing.T) {
+	saved := auth
+	auth = nil
+	t.Cleanup(func() { auth = saved })
+
+	called := false
+	handler := authMiddleware(func(w http.ResponseWriter, r *http.Request) { called = true })
+	req := httptest.NewRequest(http.MethodGet, "/api/orders", nil)
+	req.Header.Set("Authorization", "Bearer nonsense")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	if !called || rec.Code != http.StatusOK {
+		t.Errorf("without JWT_SECRET the request should pass through; called = %t, status = %d", called, rec.Code)
+	}
+}
+
+// unsignedToken returns claims as an alg=none token, which must be refused
+func unsignedToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+// baggageKeys lists the W3C Baggage entries copied onto server spans and
+// request metrics, configurable via BAGGAGE_KEYS (comma-separated)
+var baggageKeys = loadBaggageKeys()
+
+func loadBaggageKeys() []string {
+	value := os.Getenv("BAGGAGE_KEYS")
+	if value == "" {
+		value = "loadtest.run_id,tenant"
+	}
+
+	return splitList(value)
+}
+
+// baggageMetricValues bounds each baggage key's values on request metrics to
+// BAGGAGE_MAX_CARDINALITY (default 20), since callers choose them freely;
+// spans carry the raw values
+var baggageMetricValues = loadBaggageMetricValues()
+
+func loadBaggageMetricValues() map[attribute.Key]*boundedValues {
+	limit := max(getEnvInt("BAGGAGE_MAX_CARDINALITY", 20), 1)
+	values := make(map[attribute.Key]*boundedValues, len(baggageKeys))
+	for _, key := range baggageKeys {
+		values[attribute.Key(key)] = newBoundedValues(limit)
+	}
+	return values
+}
+
+// baggageAttributes returns attributes for the selected baggage entries present in ctx
+func baggageAttributes(ctx context.Context) []attribute.KeyValue {
+	bag := baggage.FromContext(ctx)
+	if bag.Len() == 0 {
+		return nil
+	}
+
+	var attrs []attribute.KeyValue
+	for _, key := range baggageKeys {
+		if member := bag.Member(key); member.Key() != "" {
+			attrs = append(attrs, attribute.String(key, member.Value()))
+		}
+	}
+	return attrs
+}
+
+// baggageMetricAttributes returns attrs from baggageAttributes with their
+// values bounded for use as metric attributes
+func baggageMetricAttributes(attrs []attribute.KeyValue) []attribute.KeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	bounded := make([]attribute.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		bounded = append(bounded, attr.Key.String(baggageMetricValues[attr.Key].value(attr.Value.AsString())))
+	}
+	return bounded
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+func TestBaggageMetricAttributesBounded(t *testing.T) {
+	saved := baggageMetricValues
+	baggageMetricValues = map[attribute.Key]*boundedValues{"tenant": newBoundedValues(2)}
+	t.Cleanup(func() { baggageMetricValues = saved })
+
+	tests := []struct {
+		tenant     string
+		wantSpan   string
+		wantMetric string
+	}{
+		{"a", "a", "a"},
+		{"b", "b", "b"},
+		{"c", "c", otherValue},
+		{"a", "a", "a"},
+		{"d", "d", otherValue},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprint(i, tt.tenant), func(t *testing.T) {
+			member, err := baggage.NewMember("tenant", tt.tenant)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bag, err := baggage.New(member)
+			if err != nil {
+				t.Fatal(err)
+			}
+			spanAttrs := baggageAttributes(baggage.ContextWithBaggage(context.Background(), bag))
+			metricAttrs := baggageMetricAttributes(spanAttrs)
+			if len(spanAttrs) != 1 || len(metricAttrs) != 1 {
+				t.Fatalf("got span %v and metric %v attributes, want one tenant each", spanAttrs, metricAttrs)
+			}
+			if got := spanAttrs[0].Value.AsString(); got != tt.wantSpan {
+				t.Errorf("span tenant = %q, want %q", got, tt.wantSpan)
+			}
+			if got := metricAttrs[0].Value.AsString(); got != tt.wantMetric {
+				t.Errorf("metric tenant = %q, want %q", got, tt.wantMetric)
+			}
+		})
+	}
+}
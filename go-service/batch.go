@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// maxBatchItems caps the items per /api/compute/batch request
+	maxBatchItems = 1000
+	// maxBatchConcurrency caps how many items are processed at once
+	maxBatchConcurrency = 50
+)
+
+type BatchItem struct {
+	ID    string  `json:"id"`
+	Value float64 `json:"value"`
+	// Fail forces this item to fail, for deterministic partial failures
+	Fail bool `json:"fail,omitempty"`
+}
+
+type BatchRequest struct {
+	Items []BatchItem `json:"items"`
+	// Count generates that many random items when Items is empty
+	Count       int     `json:"count"`
+	FailureRate float64 `json:"failureRate"`
+	Concurrency int     `json:"concurrency"`
+}
+
+type BatchItemResult struct {
+	Index      int      `json:"index"`
+	ID         string   `json:"id"`
+	Result     *float64 `json:"result,omitempty"`
+	DurationMs int      `json:"durationMs"`
+	Error      string   `json:"error,omitempty"`
+}
+
+type BatchResponse struct {
+	Service   string            `json:"service"`
+	Timestamp string            `json:"timestamp"`
+	Total     int               `json:"total"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Results   []BatchItemResult `json:"results"`
+}
+
+func (b *BatchRequest) validate() []FieldError {
+	var fields []FieldError
+	switch {
+	case len(b.Items) > 0 && b.Count != 0:
+		fields = append(fields, FieldError{Field: "count", Reason: reasonOutOfRange, Message: "must be omitted when items are given"})
+	case len(b.Items) == 0 && b.Count == 0:
+		fields = append(fields, FieldError{Field: "items", Reason: reasonRequired, Message: "items or count is required"})
+	case len(b.Items) > maxBatchItems:
+		fields = append(fields, FieldError{Field: "items", Reason: reasonOutOfRange, Message: fmt.Sprintf("must have at most %d items", maxBatchItems)})
+	case b.Count < 0 || b.Count > maxBatchItems:
+		fields = append(fields, FieldError{Field: "count", Reason: reasonOutOfRange, Message: fmt.Sprintf("must be between 1 and %d", maxBatchItems)})
+	}
+	if b.FailureRate < 0 || b.FailureRate > 1 {
+		fields = append(fields, FieldError{Field: "failureRate", Reason: reasonOutOfRange, Message: "must be a number between 0 and 1"})
+	}
+	if b.Concurrency < 0 || b.Concurrency > maxBatchConcurrency {
+		fields = append(fields, FieldError{Field: "concurrency", Reason: reasonOutOfRange, Message: fmt.Sprintf("must be between 1 and %d", maxBatchConcurrency)})
+	}
+	return fields
+}
+
+// computeBatchItem processes one batch item inside its own child span
+func computeBatchItem(ctx context.Context, index int, item BatchItem, failureRate float64) BatchItemResult {
+	ctx, span := tracer.Start(ctx, "compute-batch-item",
+		trace.WithAttributes(
+			attribute.Int("batch.item.index", index),
+			attribute.String("batch.item.id", item.ID),
+		),
+	)
+	defer span.End()
+
+	result := BatchItemResult{Index: index, ID: item.ID, DurationMs: rand.Intn(20) + 5}
+	if err := sleepContext(ctx, time.Duration(result.DurationMs)*time.Millisecond); err != nil {
+		recordCancellation(ctx, span, "batch-item")
+		result.Error = err.Error()
+		return result
+	}
+
+	if item.Fail || rand.Float64() < failureRate {
+		err := fmt.Errorf("item %s failed", item.ID)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		result.Error = err.Error()
+		return result
+	}
+
+	value := item.Value * 3.14159
+	span.SetAttributes(attribute.Float64("batch.item.result", value))
+	result.Result = &value
+	return result
+}
+
+// batchComputeHandler processes each item of a POSTed batch under its own
+// child span and returns per-item results. Failed items are reported
+// alongside successful ones; only a batch where every item failed is an error.
+func batchComputeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := tracer.Start(ctx, "compute-batch-request")
+	defer span.End()
+
+	var request BatchRequest
+	if !decodeBody(ctx, w, r, &request) {
+		return
+	}
+	for i := range request.Count {
+		request.Items = append(request.Items, BatchItem{ID: fmt.Sprintf("item-%d", i), Value: rand.Float64() * 100})
+	}
+	n := len(request.Items)
+	concurrency := request.Concurrency
+	if concurrency == 0 {
+		concurrency = 10
+	}
+
+	for i := range request.Items {
+		if request.Items[i].ID == "" {
+			request.Items[i].ID = fmt.Sprintf("item-%d", i)
+		}
+	}
+
+	results := make([]BatchItemResult, n)
+	var g errgroup.Group
+	g.SetLimit(concurrency)
+	for i, item := range request.Items {
+		g.Go(func() error {
+			results[i] = computeBatchItem(ctx, i, item, request.FailureRate)
+			return nil
+		})
+	}
+	g.Wait()
+
+	failed := 0
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+		}
+	}
+	span.SetAttributes(
+		attribute.Int("batch.size", n),
+		attribute.Int("batch.concurrency", concurrency),
+		attribute.Int("batch.failed", failed),
+	)
+	if ctx.Err() != nil {
+		// The client is gone; there is nobody to respond to
+		return
+	}
+	if failed > 0 {
+		span.AddEvent("Partial failure", trace.WithAttributes(attribute.Int("batch.failed", failed)))
+	}
+
+	response := BatchResponse{
+		Service:   serviceName,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Total:     n,
+		Succeeded: n - failed,
+		Failed:    failed,
+		Results:   results,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if failed == n {
+		span.SetStatus(codes.Error, "all batch items failed")
+		setErrorType(ctx, span, errorTypeInjected)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"log"
+	"runtime"
+	"runtime/debug"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Build identity, set with
+//
+//	go build -ldflags "-X main.gitCommit=$(git rev-parse HEAD) -X main.buildTime=$(date -u +%FT%TZ)"
+//
+// and otherwise taken from the VCS stamp Go embeds when building from a checkout
+var (
+	gitCommit string
+	buildTime string
+)
+
+// buildInfo describes the running binary
+type buildInfo struct {
+	Commit    string `json:"commit"`
+	Time      string `json:"time"`
+	GoVersion string `json:"goVersion"`
+	Modified  bool   `json:"modified"`
+}
+
+var build = readBuildInfo()
+
+func readBuildInfo() buildInfo {
+	info := buildInfo{Commit: gitCommit, Time: buildTime, GoVersion: runtime.Version()}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.Time == "" {
+					info.Time = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.Time == "" {
+		info.Time = "unknown"
+	}
+	return info
+}
+
+// attributes identify the build on the resource and the build info gauge
+func (b buildInfo) attributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("vcs.ref.head.revision", b.Commit),
+		attribute.String("build.time", b.Time),
+		attribute.String("build.go_version", b.GoVersion),
+		attribute.Bool("build.vcs.modified", b.Modified),
+	}
+}
+
+// initBuildInfoMetric registers service.build.info, an info-style gauge that
+// is always 1 and carries the build identity as attributes, so dashboards can
+// group or join any series by version
+func initBuildInfoMetric() error {
+	log.Printf("Build info: commit=%s time=%s go=%s modified=%t", build.Commit, build.Time, build.GoVersion, build.Modified)
+	attrs := metric.WithAttributes(append(build.attributes(),
+		attribute.String("service.version", serviceVersion),
+	)...)
+	_, err := meter.Int64ObservableGauge(
+		"service.build.info",
+		metric.WithDescription("Build information of the running binary; always 1"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(1, attrs)
+			return nil
+		}),
+	)
+	return err
+}
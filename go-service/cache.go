@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type cacheEntry struct {
+	value   ComputeResponse
+	expires time.Time
+}
+
+// computeCache is an in-memory TTL cache in front of the compute path
+type computeCache struct {
+	mu      sync.RWMutex
+	entries map[string]cacheEntry
+	ttl     time.Duration
+
+	hits   atomic.Int64
+	misses atomic.Int64
+
+	lookups metric.Int64Counter
+}
+
+func newComputeCache(ttl time.Duration) (*computeCache, error) {
+	c := &computeCache{
+		entries: make(map[string]cacheEntry),
+		ttl:     ttl,
+	}
+
+	var err error
+	c.lookups, err = meter.Int64Counter(
+		"cache.lookups",
+		metric.WithDescription("The number of compute cache lookups, by hit or miss"),
+		metric.WithUnit("{lookup}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = meter.Float64ObservableGauge(
+		"cache.hit_ratio",
+		metric.WithDescription("Ratio of compute cache lookups that were hits"),
+		metric.WithUnit("1"),
+		metric.WithFloat64Callback(func(_ context.Context, o metric.Float64Observer) error {
+			hits, misses := c.hits.Load(), c.misses.Load()
+			if total := hits + misses; total > 0 {
+				o.Observe(float64(hits) / float64(total))
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Get looks up a cached compute response inside a "cache.get" span
+func (c *computeCache) Get(ctx context.Context, key string) (ComputeResponse, bool) {
+	ctx, span := tracer.Start(ctx, "cache.get",
+		trace.WithAttributes(attribute.String("cache.key", key)),
+	)
+	defer span.End()
+
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+
+	hit := ok && time.Now().Before(entry.expires)
+	if hit {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+
+	span.SetAttributes(attribute.Bool("cache.hit", hit))
+	c.lookups.Add(ctx, 1, metric.WithAttributes(attribute.Bool("cache.hit", hit)))
+
+	return entry.value, hit
+}
+
+// Set stores a compute response inside a "cache.set" span
+func (c *computeCache) Set(ctx context.Context, key string, value ComputeResponse) {
+	_, span := tracer.Start(ctx, "cache.set",
+		trace.WithAttributes(
+			attribute.String("cache.key", key),
+			attribute.Int64("cache.ttl_ms", c.ttl.Milliseconds()),
+		),
+	)
+	defer span.End()
+
+	c.mu.Lock()
+	c.entries[key] = cacheEntry{value: value, expires: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+}
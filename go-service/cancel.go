@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// statusClientClosedRequest is the de facto (nginx) status for requests the
+// client abandoned before a response was written
+const statusClientClosedRequest = 499
+
+var cancelledRequests metric.Int64Counter
+
+func initCancellationMetrics() error {
+	var err error
+	cancelledRequests, err = meter.Int64Counter(
+		"http.server.cancelled_requests",
+		metric.WithDescription("The number of HTTP requests abandoned because the client disconnected"),
+		metric.WithUnit("{request}"),
+	)
+	return err
+}
+
+// sleepContext waits for d or until ctx is done, returning ctx.Err() if the
+// wait was cut short
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// recordCancellation marks span as abandoned because ctx ended, noting how
+// far the work got
+func recordCancellation(ctx context.Context, span trace.Span, stage string) {
+	err := context.Cause(ctx)
+	span.AddEvent("request.cancelled", trace.WithAttributes(
+		attribute.String("cancellation.stage", stage),
+		attribute.String("cancellation.cause", err.Error()),
+	))
+	if errors.Is(err, context.Canceled) {
+		span.SetStatus(codes.Error, "client cancelled request")
+	} else {
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// maxCardinality caps the unique attribute values per /api/cardinality request
+const maxCardinality = 100000
+
+var cardinalityCounter metric.Int64Counter
+
+type CardinalityResponse struct {
+	Service   string `json:"service"`
+	Timestamp string `json:"timestamp"`
+	Series    int    `json:"series"`
+	Prefix    string `json:"prefix"`
+}
+
+func initCardinalityMetrics() error {
+	var err error
+	cardinalityCounter, err = meter.Int64Counter(
+		"cardinality.stress",
+		metric.WithDescription("Deliberately high-cardinality counter emitted by /api/cardinality"),
+		metric.WithUnit("{increment}"),
+	)
+	return err
+}
+
+// cardinalityHandler increments cardinality.stress once for each of ?n= unique
+// stress.id values (default 100), named <prefix>-<i> so repeated calls with a
+// new ?prefix= keep growing the series count. It is disabled unless the
+// cardinality-stress feature flag is on.
+func cardinalityHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := tracer.Start(ctx, "cardinality-request")
+	defer span.End()
+
+	if !flagEnabled(ctx, flagCardinalityStress) {
+		writeError(w, http.StatusForbidden, fmt.Sprintf("Enable the %s feature flag to use this endpoint", flagCardinalityStress))
+		return
+	}
+
+	n := 100
+	if value := r.URL.Query().Get("n"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > maxCardinality {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("n must be an integer between 1 and %d", maxCardinality))
+			return
+		}
+		n = parsed
+	}
+	prefix := r.URL.Query().Get("prefix")
+	if prefix == "" {
+		prefix = "series"
+	}
+
+	for i := 0; i < n; i++ {
+		cardinalityCounter.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("stress.id", fmt.Sprintf("%s-%d", prefix, i)),
+		))
+	}
+	span.SetAttributes(
+		attribute.Int("cardinality.series", n),
+		attribute.String("cardinality.prefix", prefix),
+	)
+	logger.WarnContext(ctx, "Emitted high-cardinality metric series", "series", n, "prefix", prefix)
+
+	response := CardinalityResponse{
+		Service:   serviceName,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Series:    n,
+		Prefix:    prefix,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
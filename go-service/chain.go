@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// httpClient is shared by all outbound calls the service makes. Its transport
+// creates CLIENT spans with peer attributes and propagates trace context, the
+// request ID, and the remaining deadline, behind a per-host circuit breaker,
+// and retries transient failures.
+var httpClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &retryTransport{
+		next: &breakerTransport{next: &deadlineTransport{next: &requestIDTransport{next: otelhttp.NewTransport(&peerServiceTransport{next: http.DefaultTransport})}}},
+	},
+}
+
+type ChainResponse struct {
+	Service            string          `json:"service"`
+	Timestamp          string          `json:"timestamp"`
+	DownstreamURL      string          `json:"downstreamUrl"`
+	DownstreamStatus   int             `json:"downstreamStatus"`
+	DownstreamResponse json.RawMessage `json:"downstreamResponse"`
+}
+
+// downstreamURL returns the URL /api/chain calls, configurable via DOWNSTREAM_URL
+func downstreamURL() string {
+	if url := os.Getenv("DOWNSTREAM_URL"); url != "" {
+		return url
+	}
+	return "http://localhost:8080/api/compute"
+}
+
+func chainHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := tracer.Start(ctx, "chain-request")
+	defer span.End()
+
+	url := downstreamURL()
+	span.SetAttributes(attribute.String("downstream.url", url))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		writeError(w, http.StatusInternalServerError, "Invalid downstream URL")
+		return
+	}
+
+	downstreamStart := time.Now()
+	resp, err := httpClient.Do(req)
+	recordServerTiming(ctx, "downstream", time.Since(downstreamStart))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		setErrorType(ctx, span, downstreamErrorType(err))
+		logger.ErrorContext(ctx, "Downstream call failed", "url.full", url, "error", err)
+		writeError(w, http.StatusBadGateway, fmt.Sprintf("Downstream call failed: %v", err))
+		return
+	}
+	defer resp.Body.Close()
+
+	span.SetAttributes(attribute.Int("downstream.status_code", resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, fmt.Sprintf("downstream returned HTTP %d", resp.StatusCode))
+		setErrorType(ctx, span, errorTypeDownstream)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		setErrorType(ctx, span, downstreamErrorType(err))
+		writeError(w, http.StatusBadGateway, "Failed to read downstream response")
+		return
+	}
+	if !json.Valid(body) {
+		body, _ = json.Marshal(string(body))
+	}
+
+	response := ChainResponse{
+		Service:            serviceName,
+		Timestamp:          time.Now().UTC().Format(time.RFC3339),
+		DownstreamURL:      url,
+		DownstreamStatus:   resp.StatusCode,
+		DownstreamResponse: body,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// breakerState is the state of one downstream's circuit breaker; the values
+// are what the circuit_breaker.state gauge reports
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// errCircuitOpen is returned for calls the breaker short-circuits
+var errCircuitOpen = errors.New("circuit breaker is open")
+
+type breaker struct {
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// circuitBreakers tracks one breaker per downstream host. A breaker opens
+// after threshold consecutive failures (transport errors or 5xx), rejects
+// calls for openFor, then lets a single probe through half-open to decide
+// whether to close again.
+type circuitBreakers struct {
+	mu        sync.Mutex
+	breakers  map[string]*breaker
+	threshold int
+	openFor   time.Duration
+
+	shortCircuited metric.Int64Counter
+	transitions    metric.Int64Counter
+}
+
+var breakers *circuitBreakers
+
+// initCircuitBreaker enables circuit breaking of outbound calls when
+// CIRCUIT_BREAKER_FAILURES is positive
+func initCircuitBreaker() error {
+	threshold := getEnvInt("CIRCUIT_BREAKER_FAILURES", 5)
+	if threshold <= 0 {
+		return nil
+	}
+	cb := &circuitBreakers{
+		breakers:  make(map[string]*breaker),
+		threshold: threshold,
+		openFor:   getEnvDuration("CIRCUIT_BREAKER_OPEN_DURATION", 30*time.Second),
+	}
+
+	var err error
+	cb.shortCircuited, err = meter.Int64Counter(
+		"circuit_breaker.short_circuited",
+		metric.WithDescription("The number of outbound calls rejected without being attempted because the circuit was open"),
+		metric.WithUnit("{call}"),
+	)
+	if err != nil {
+		return err
+	}
+
+	cb.transitions, err = meter.Int64Counter(
+		"circuit_breaker.transitions",
+		metric.WithDescription("The number of circuit breaker state changes"),
+		metric.WithUnit("{transition}"),
+	)
+	if err != nil {
+		return err
+	}
+
+	state, err := meter.Int64ObservableGauge(
+		"circuit_breaker.state",
+		metric.WithDescription("Circuit breaker state per downstream: 0 closed, 1 open, 2 half-open"),
+	)
+	if err != nil {
+		return err
+	}
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		cb.mu.Lock()
+		defer cb.mu.Unlock()
+		for name, b := range cb.breakers {
+			o.ObserveInt64(state, int64(b.state), metric.WithAttributes(attribute.String("circuit_breaker.name", name)))
+		}
+		return nil
+	}, state)
+	if err != nil {
+		return err
+	}
+
+	breakers = cb
+	log.Printf("Circuit breaker enabled: opens after %d consecutive failures for %s", threshold, cb.openFor)
+	return nil
+}
+
+// setState moves b to state, recording the transition on span and in metrics.
+// Callers hold cb.mu.
+func (cb *circuitBreakers) setState(ctx context.Context, span trace.Span, name string, b *breaker, state breakerState) {
+	if b.state == state {
+		return
+	}
+	from := b.state
+	b.state = state
+	span.AddEvent("circuit_breaker.state_change", trace.WithAttributes(
+		attribute.String("circuit_breaker.name", name),
+		attribute.String("circuit_breaker.from", from.String()),
+		attribute.String("circuit_breaker.to", state.String()),
+		attribute.Int("circuit_breaker.consecutive_failures", b.failures),
+	))
+	cb.transitions.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("circuit_breaker.name", name),
+		attribute.String("circuit_breaker.state", state.String()),
+	))
+	log.Printf("Circuit breaker %s: %s -> %s", name, from, state)
+}
+
+// allow reports whether a call to name may proceed, moving an open breaker
+// to half-open once openFor has elapsed
+func (cb *circuitBreakers) allow(ctx context.Context, span trace.Span, name string) bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	b, ok := cb.breakers[name]
+	if !ok {
+		b = &breaker{}
+		cb.breakers[name] = b
+	}
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < cb.openFor {
+			return false
+		}
+		cb.setState(ctx, span, name, b, breakerHalfOpen)
+		b.probing = true
+		return true
+	case breakerHalfOpen:
+		// Only the single probe is let through until it reports back
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// release ends a call without an outcome, freeing the half-open probe slot
+func (cb *circuitBreakers) release(name string) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.breakers[name].probing = false
+}
+
+// record feeds a call outcome back into name's breaker
+func (cb *circuitBreakers) record(ctx context.Context, span trace.Span, name string, failed bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	b := cb.breakers[name]
+	b.probing = false
+	if !failed {
+		b.failures = 0
+		cb.setState(ctx, span, name, b, breakerClosed)
+		return
+	}
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= cb.threshold {
+		b.openedAt = time.Now()
+		cb.setState(ctx, span, name, b, breakerOpen)
+	}
+}
+
+// breakerTransport applies the circuit breakers to outbound requests, keyed by
+// host. Short-circuited calls fail with errCircuitOpen without reaching next.
+type breakerTransport struct {
+	next http.RoundTripper
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if breakers == nil {
+		return t.next.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	span := trace.SpanFromContext(ctx)
+	name := req.URL.Host
+	if !breakers.allow(ctx, span, name) {
+		span.AddEvent("circuit_breaker.short_circuited", trace.WithAttributes(
+			attribute.String("circuit_breaker.name", name),
+		))
+		breakers.shortCircuited.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("circuit_breaker.name", name),
+		))
+		return nil, errCircuitOpen
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if errors.Is(err, context.Canceled) {
+		// Our caller gave up; that says nothing about the downstream's health
+		breakers.release(name)
+		return resp, err
+	}
+	breakers.record(ctx, span, name, err != nil || resp.StatusCode >= http.StatusInternalServerError)
+	return resp, err
+}
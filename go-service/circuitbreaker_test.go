@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// roundTripFunc adapts a function to http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// respond returns a RoundTripper answering with status, counting its calls
+func respond(status *int, calls *int) http.RoundTripper {
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		*calls++
+		rec := httptest.NewRecorder()
+		rec.WriteHeader(*status)
+		return rec.Result(), nil
+	})
+}
+
+func TestBreakerTransport(t *testing.T) {
+	t.Setenv("CIRCUIT_BREAKER_FAILURES", "2")
+	t.Setenv("CIRCUIT_BREAKER_OPEN_DURATION", "1h")
+	saved := breakers
+	t.Cleanup(func() { breakers = saved })
+	if err := initCircuitBreaker(); err != nil {
+		t.Fatal(err)
+	}
+
+	status, calls := http.StatusInternalServerError, 0
+	transport := &breakerTransport{next: respond(&status, &calls)}
+	send := func() error {
+		req := httptest.NewRequest(http.MethodGet, "http://downstream/api", nil)
+		resp, err := transport.RoundTrip(req)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	// elapse pretends the open duration has passed
+	elapse := func() { breakers.breakers["downstream"].openedAt = time.Now().Add(-2 * time.Hour) }
+	state := func() breakerState { return breakers.breakers["downstream"].state }
+
+	steps := []struct {
+		name        string
+		before      func()
+		status      int
+		wantOpenErr bool
+		wantState   breakerState
+	}{
+		{"first failure stays closed", nil, http.StatusInternalServerError, false, breakerClosed},
+		{"threshold opens", nil, http.StatusBadGateway, false, breakerOpen},
+		{"open short-circuits", nil, http.StatusOK, true, breakerOpen},
+		{"failed probe reopens", elapse, http.StatusServiceUnavailable, false, breakerOpen},
+		{"reopened short-circuits", nil, http.StatusOK, true, breakerOpen},
+		{"successful probe closes", elapse, http.StatusOK, false, breakerClosed},
+		{"client errors do not count", nil, http.StatusNotFound, false, breakerClosed},
+	}
+	for _, step := range steps {
+		if step.before != nil {
+			step.before()
+		}
+		status = step.status
+		before := calls
+		err := send()
+		if step.wantOpenErr {
+			if !errors.Is(err, errCircuitOpen) {
+				t.Errorf("%s: err = %v, want errCircuitOpen", step.name, err)
+			}
+			if calls != before {
+				t.Errorf("%s: short-circuited call reached the downstream", step.name)
+			}
+		} else if err != nil {
+			t.Errorf("%s: err = %v", step.name, err)
+		}
+		if got := state(); got != step.wantState {
+			t.Errorf("%s: state = %s, want %s", step.name, got, step.wantState)
+		}
+	}
+}
+
+func TestBreakerHalfOpenSingleProbe(t *testing.T) {
+	t.Setenv("CIRCUIT_BREAKER_FAILURES", "1")
+	t.Setenv("CIRCUIT_BREAKER_OPEN_DURATION", "1h")
+	saved := breakers
+	t.Cleanup(func() { breakers = saved })
+	if err := initCircuitBreaker(); err != nil {
+		t.Fatal(err)
+	}
+	cb := breakers
+	ctx, span := context.Background(), trace.SpanFromContext(context.Background())
+	cb.breakers["downstream"] = &breaker{state: breakerOpen, openedAt: time.Now().Add(-2 * time.Hour)}
+
+	if !cb.allow(ctx, span, "downstream") {
+		t.Fatal("open breaker past its open duration refused the probe")
+	}
+	if got := cb.breakers["downstream"].state; got != breakerHalfOpen {
+		t.Fatalf("state = %s, want half_open", got)
+	}
+	if cb.allow(ctx, span, "downstream") {
+		t.Error("a second call was let through while the probe was in flight")
+	}
+	cb.release("downstream")
+	if !cb.allow(ctx, span, "downstream") {
+		t.Error("a released probe slot was not reused")
+	}
+}
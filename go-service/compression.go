@@ -0,0 +1,403 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Compression settings from COMPRESSION_ENABLED and COMPRESSION_MIN_BYTES
+var (
+	compressionEnabled  bool
+	compressionMinBytes int
+)
+
+var (
+	compressionRatio    metric.Float64Histogram
+	compressionDuration metric.Float64Histogram
+)
+
+// supportedEncodings lists the response encodings we produce, in order of
+// preference when the client weights them equally
+var supportedEncodings = []string{"gzip", "deflate"}
+
+func initCompression() error {
+	compressionEnabled = getEnvBool("COMPRESSION_ENABLED", true)
+	compressionMinBytes = getEnvInt("COMPRESSION_MIN_BYTES", 1024)
+
+	var err error
+	compressionRatio, err = meter.Float64Histogram(
+		"http.server.response.compression.ratio",
+		metric.WithDescription("Uncompressed to compressed size ratio of compressed HTTP responses"),
+		metric.WithUnit("1"),
+		metric.WithExplicitBucketBoundaries(1, 1.5, 2, 3, 4, 6, 8, 12, 16, 32),
+	)
+	if err != nil {
+		return err
+	}
+
+	compressionDuration, err = meter.Float64Histogram(
+		"http.server.response.compression.duration",
+		metric.WithDescription("Time spent compressing HTTP response bodies"),
+		metric.WithUnit("s"),
+	)
+	return err
+}
+
+// negotiateEncoding picks a supported encoding from an Accept-Encoding header,
+// honouring q-values (q=0 refuses an encoding). "*" covers only the encodings
+// the header does not name, so "*, gzip;q=0" still refuses gzip. It returns
+// "" for identity.
+func negotiateEncoding(header string) string {
+	explicit := make(map[string]float64)
+	wildcard := 0.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if name == "*" {
+			wildcard = q
+		} else if name != "" {
+			explicit[name] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range supportedEncodings {
+		q, ok := explicit[encoding]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// compressWriter buffers the start of a response until it knows whether the
+// body is worth compressing, then either streams it through a gzip/deflate
+// (zlib) writer or passes it through untouched
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	status   int
+	buf      []byte
+	decided  bool
+	encoder  io.WriteCloser
+	counter  *countingWriter
+	raw      int64
+	elapsed  time.Duration
+}
+
+// countingWriter counts the compressed bytes written to the client
+type countingWriter struct {
+	io.Writer
+	bytes int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.Writer.Write(b)
+	c.bytes += int64(n)
+	return n, err
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.status == 0 {
+		cw.status = code
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if !cw.decided {
+		cw.buf = append(cw.buf, b...)
+		if len(cw.buf) < compressionMinBytes {
+			return len(b), nil
+		}
+		if err := cw.start(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	return cw.write(b)
+}
+
+// start commits the headers, compressing if allowed, and writes any buffered body
+func (cw *compressWriter) start(compress bool) error {
+	cw.decided = true
+	header := cw.Header()
+	if compress && compressible(header, cw.status) {
+		cw.counter = &countingWriter{Writer: cw.ResponseWriter}
+		// Content-Encoding: deflate is the zlib format (RFC 9110 section
+		// 8.4.1.2), not a raw DEFLATE stream
+		if cw.encoding == "gzip" {
+			cw.encoder = gzip.NewWriter(cw.counter)
+		} else {
+			encoder, err := zlib.NewWriterLevel(cw.counter, zlib.DefaultCompression)
+			if err != nil {
+				return err
+			}
+			cw.encoder = encoder
+		}
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	buf := cw.buf
+	cw.buf = nil
+	_, err := cw.write(buf)
+	return err
+}
+
+func (cw *compressWriter) write(b []byte) (int, error) {
+	if cw.encoder == nil {
+		return cw.ResponseWriter.Write(b)
+	}
+	start := time.Now()
+	n, err := cw.encoder.Write(b)
+	cw.elapsed += time.Since(start)
+	cw.raw += int64(n)
+	return n, err
+}
+
+// compressible reports whether a response with these headers should be
+// compressed: not already encoded, not a stream, and carrying a body
+func compressible(header http.Header, status int) bool {
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	if strings.HasPrefix(header.Get("Content-Type"), "text/event-stream") {
+		return false
+	}
+	return status != http.StatusNoContent && status != http.StatusNotModified && status >= http.StatusOK
+}
+
+// Flush commits to compressing whatever has been written so far so streaming
+// handlers are not held back by the minimum-size buffer
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		cw.start(true)
+	}
+	if flusher, ok := cw.encoder.(interface{ Flush() error }); ok {
+		start := time.Now()
+		flusher.Flush()
+		cw.elapsed += time.Since(start)
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack hands the raw connection to the WebSocket handler; nothing written
+// afterwards goes through the compressor
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	cw.decided = true
+	return hijacker.Hijack()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// finish writes out a short buffered body uncompressed, or closes the
+// encoder and records the compression telemetry
+func (cw *compressWriter) finish(ctx context.Context, route string) {
+	if !cw.decided {
+		if cw.status == 0 {
+			// The handler wrote nothing; let net/http send its default response
+			return
+		}
+		cw.start(false)
+		return
+	}
+	if cw.encoder == nil {
+		return
+	}
+
+	start := time.Now()
+	cw.encoder.Close()
+	cw.elapsed += time.Since(start)
+
+	ratio := 0.0
+	if cw.counter.bytes > 0 {
+		ratio = float64(cw.raw) / float64(cw.counter.bytes)
+	}
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("http.response.compression.encoding", cw.encoding),
+		attribute.Int64("http.response.body.uncompressed_size", cw.raw),
+		attribute.Float64("http.response.compression.ratio", ratio),
+	)
+	attrs := metric.WithAttributes(
+		attribute.String("http.route", route),
+		attribute.String("http.response.compression.encoding", cw.encoding),
+	)
+	compressionRatio.Record(ctx, ratio, attrs)
+	compressionDuration.Record(ctx, cw.elapsed.Seconds(), attrs)
+}
+
+// compressionMiddleware compresses responses with gzip or deflate when the
+// client's Accept-Encoding allows it and the body is at least
+// COMPRESSION_MIN_BYTES
+func compressionMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !compressionEnabled {
+			next(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" {
+			next(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.finish(r.Context(), httpRoute(r))
+		next(cw, r)
+	}
+}
+
+// Request decompression settings from REQUEST_DECOMPRESSION_ENABLED and
+// REQUEST_MAX_DECOMPRESSED_BYTES
+var (
+	requestDecompressionEnabled bool
+	requestMaxDecompressedBytes int64
+)
+
+var decompressionDuration metric.Float64Histogram
+
+func initRequestDecompression() error {
+	requestDecompressionEnabled = getEnvBool("REQUEST_DECOMPRESSION_ENABLED", true)
+	requestMaxDecompressedBytes = int64(max(getEnvInt("REQUEST_MAX_DECOMPRESSED_BYTES", 8<<20), 1))
+
+	var err error
+	decompressionDuration, err = meter.Float64Histogram(
+		"http.server.request.decompression.duration",
+		metric.WithDescription("Time spent decompressing HTTP request bodies"),
+		metric.WithUnit("s"),
+	)
+	return err
+}
+
+// decompressReader inflates a gzip request body as the handler reads it,
+// timing the work and counting bytes on both sides
+type decompressReader struct {
+	gz         *gzip.Reader
+	body       io.ReadCloser
+	compressed *countingReader
+	raw        int64
+	elapsed    time.Duration
+}
+
+// countingReader counts the compressed bytes read from the client
+type countingReader struct {
+	io.Reader
+	bytes int64
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.Reader.Read(b)
+	c.bytes += int64(n)
+	return n, err
+}
+
+func (d *decompressReader) Read(b []byte) (int, error) {
+	start := time.Now()
+	n, err := d.gz.Read(b)
+	d.elapsed += time.Since(start)
+	d.raw += int64(n)
+	return n, err
+}
+
+func (d *decompressReader) Close() error {
+	d.gz.Close()
+	return d.body.Close()
+}
+
+// finish records the decompression telemetry once the handler is done
+func (d *decompressReader) finish(ctx context.Context, route string) {
+	ratio := 0.0
+	if d.compressed.bytes > 0 {
+		ratio = float64(d.raw) / float64(d.compressed.bytes)
+	}
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("http.request.compression.encoding", "gzip"),
+		attribute.Int64("http.request.body.compressed_size", d.compressed.bytes),
+		attribute.Int64("http.request.body.uncompressed_size", d.raw),
+		attribute.Float64("http.request.compression.ratio", ratio),
+		attribute.Float64("http.request.decompression.duration_ms", float64(d.elapsed.Microseconds())/1000),
+	)
+	decompressionDuration.Record(ctx, d.elapsed.Seconds(), metric.WithAttributes(
+		attribute.String("http.route", route),
+		attribute.String("http.request.compression.encoding", "gzip"),
+	))
+}
+
+// decompressionMiddleware accepts Content-Encoding: gzip request bodies,
+// presenting the handler with the decompressed body. The compressed body is
+// capped at the usual request limit and the decompressed one at
+// REQUEST_MAX_DECOMPRESSED_BYTES (handlers apply their own limits as well);
+// other encodings get 415 with the supported one listed.
+func decompressionMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+		if !requestDecompressionEnabled || encoding == "" || encoding == "identity" {
+			next(w, r)
+			return
+		}
+		ctx := r.Context()
+		if encoding != "gzip" && encoding != "x-gzip" {
+			w.Header().Set("Accept-Encoding", "gzip")
+			writeError(w, http.StatusUnsupportedMediaType, fmt.Sprintf("Unsupported Content-Encoding %q", encoding))
+			return
+		}
+
+		compressed := &countingReader{Reader: http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)}
+		gz, err := gzip.NewReader(compressed)
+		if err != nil {
+			rejectInvalid(ctx, w, r, []FieldError{{Field: "Content-Encoding", Reason: reasonBadEncoding, Message: "body is not valid gzip"}})
+			return
+		}
+		d := &decompressReader{gz: gz, body: r.Body, compressed: compressed}
+		defer d.finish(ctx, httpRoute(r))
+
+		r.Body = http.MaxBytesReader(w, d, requestMaxDecompressedBytes)
+		r.ContentLength = -1
+		r.Header.Del("Content-Length")
+		r.Header.Del("Content-Encoding")
+		next(w, r)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"empty", "", ""},
+		{"identity only", "identity", ""},
+		{"gzip", "gzip", "gzip"},
+		{"deflate", "deflate", "deflate"},
+		{"equal weights prefer gzip", "deflate, gzip", "gzip"},
+		{"higher q wins", "gzip;q=0.5, deflate;q=0.8", "deflate"},
+		{"case and spaces", " GZIP ; q=1 ", "gzip"},
+		{"q=0 refuses", "gzip;q=0", ""},
+		{"q=0 falls back", "gzip;q=0, deflate", "deflate"},
+		{"unsupported only", "br, zstd", ""},
+		{"wildcard", "*", "gzip"},
+		{"wildcard with explicit refusal", "*, gzip;q=0", "deflate"},
+		{"explicit refusal before wildcard", "gzip;q=0, *", "deflate"},
+		{"wildcard refusal", "*;q=0", ""},
+		{"wildcard refusal keeps named", "*;q=0, deflate", "deflate"},
+		{"explicit beats lower wildcard", "*;q=0.1, deflate;q=0.5", "deflate"},
+		{"malformed q is ignored", "gzip;q=abc, deflate", "deflate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := negotiateEncoding(tt.header); got != tt.want {
+				t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompressionMiddleware(t *testing.T) {
+	t.Setenv("COMPRESSION_MIN_BYTES", "16")
+	if err := initCompression(); err != nil {
+		t.Fatal(err)
+	}
+	body := strings.Repeat("compressible ", 100)
+	handler := compressionMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	})
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		wantEncoding   string
+		decode         func(io.Reader) (io.Reader, error)
+	}{
+		{"identity", "", "", func(r io.Reader) (io.Reader, error) { return r, nil }},
+		{"gzip", "gzip", "gzip", func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{"deflate is zlib", "deflate", "deflate", func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/payload", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			reader, err := tt.decode(rec.Body)
+			if err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			got, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("reading response: %v", err)
+			}
+			if string(got) != body {
+				t.Errorf("decoded body = %q, want the handler's body", got)
+			}
+		})
+	}
+}
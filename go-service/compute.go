@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"regexp"
+	"slices"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Limits on POST /api/compute input documents
+const (
+	maxComputeBodyBytes    = 128 << 10
+	maxComputeIterations   = 20
+	maxComputePayloadBytes = 64 << 10
+	maxComputeTags         = 10
+	maxComputeTagValue     = 256
+)
+
+// computeTagKey restricts tag keys to characters that are safe in an
+// attribute name
+var computeTagKey = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// ComputeRequest is the optional input document of POST /api/compute. The
+// zero value is a single iteration over a random input, which is what GET
+// requests, gRPC, and GraphQL compute.
+type ComputeRequest struct {
+	// Iterations repeats the computation, each one adding simulated work
+	Iterations int `json:"iterations"`
+	// Payload seeds the computation, so equal payloads give equal results
+	Payload string `json:"payload"`
+	// Tags are recorded on the span as compute.tag.<key>
+	Tags map[string]string `json:"tags"`
+}
+
+func (c *ComputeRequest) validate() []FieldError {
+	var fields []FieldError
+	if c.Iterations < 0 || c.Iterations > maxComputeIterations {
+		fields = append(fields, FieldError{Field: "iterations", Reason: reasonOutOfRange, Message: fmt.Sprintf("must be between 1 and %d", maxComputeIterations)})
+	}
+	if len(c.Payload) > maxComputePayloadBytes {
+		fields = append(fields, FieldError{Field: "payload", Reason: reasonTooLarge, Message: fmt.Sprintf("must be at most %d bytes", maxComputePayloadBytes)})
+	}
+	if len(c.Tags) > maxComputeTags {
+		fields = append(fields, FieldError{Field: "tags", Reason: reasonOutOfRange, Message: fmt.Sprintf("must have at most %d entries", maxComputeTags)})
+	}
+	for _, key := range slices.Sorted(maps.Keys(c.Tags)) {
+		value := c.Tags[key]
+		if !computeTagKey.MatchString(key) {
+			fields = append(fields, FieldError{Field: "tags." + key, Reason: reasonOutOfRange, Message: "key must be 1-64 letters, digits, '_', '.', or '-'"})
+		} else if len(value) > maxComputeTagValue {
+			fields = append(fields, FieldError{Field: "tags." + key, Reason: reasonTooLarge, Message: fmt.Sprintf("must be at most %d characters", maxComputeTagValue)})
+		}
+	}
+	return fields
+}
+
+// iterations is the number of iterations to run, at least one
+func (c ComputeRequest) iterations() int {
+	return max(c.Iterations, 1)
+}
+
+// seed derives the computation's input value from the payload, or returns
+// false when there is no payload and a random value should be used
+func (c ComputeRequest) seed() (int, bool) {
+	if c.Payload == "" {
+		return 0, false
+	}
+	h := fnv.New32a()
+	h.Write([]byte(c.Payload))
+	return int(h.Sum32() % 10000), true
+}
+
+// attributes describes the input document on the handler span; the payload
+// itself is only recorded by size
+func (c ComputeRequest) attributes() []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.Int("compute.iterations", c.iterations()),
+		attribute.Int("compute.payload.size", len(c.Payload)),
+	}
+	for _, key := range slices.Sorted(maps.Keys(c.Tags)) {
+		attrs = append(attrs, attribute.String("compute.tag."+key, c.Tags[key]))
+	}
+	return attrs
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// getEnvBool reads a boolean environment variable, returning fallback when unset or invalid
+func getEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvInt reads an integer environment variable, returning fallback when unset or invalid
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvFloat reads a floating-point environment variable, returning fallback when unset or invalid
+func getEnvFloat(key string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvDuration reads a duration environment variable (e.g. "30s"), returning
+// fallback when unset or invalid
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// splitList splits a comma-separated environment value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// bspConfig holds the batch span processor settings from the standard OTEL_BSP_* env vars
+type bspConfig struct {
+	MaxQueueSize       int           `json:"maxQueueSize"`
+	MaxExportBatchSize int           `json:"maxExportBatchSize"`
+	ExportTimeout      time.Duration `json:"exportTimeout"`
+	ScheduleDelay      time.Duration `json:"scheduleDelay"`
+}
+
+// MarshalJSON renders the timeouts as duration strings (e.g. "30s")
+func (c bspConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		MaxQueueSize       int    `json:"maxQueueSize"`
+		MaxExportBatchSize int    `json:"maxExportBatchSize"`
+		ExportTimeout      string `json:"exportTimeout"`
+		ScheduleDelay      string `json:"scheduleDelay"`
+	}{c.MaxQueueSize, c.MaxExportBatchSize, c.ExportTimeout.String(), c.ScheduleDelay.String()})
+}
+
+// loadBSPConfig reads OTEL_BSP_* (delays and timeouts in milliseconds), using
+// the SDK defaults for unset or invalid values
+func loadBSPConfig() bspConfig {
+	cfg := bspConfig{
+		MaxQueueSize:       max(getEnvInt("OTEL_BSP_MAX_QUEUE_SIZE", 2048), 1),
+		MaxExportBatchSize: max(getEnvInt("OTEL_BSP_MAX_EXPORT_BATCH_SIZE", 512), 1),
+		ExportTimeout:      time.Duration(max(getEnvInt("OTEL_BSP_EXPORT_TIMEOUT", 30000), 1)) * time.Millisecond,
+		ScheduleDelay:      time.Duration(max(getEnvInt("OTEL_BSP_SCHEDULE_DELAY", 5000), 1)) * time.Millisecond,
+	}
+	// The export batch can never exceed the queue
+	cfg.MaxExportBatchSize = min(cfg.MaxExportBatchSize, cfg.MaxQueueSize)
+	return cfg
+}
+
+// otlpExportConfig holds an OTLP exporter's request timeout and its retry
+// policy for failed exports
+type otlpExportConfig struct {
+	Timeout         time.Duration
+	RetryEnabled    bool
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	MaxElapsedTime  time.Duration
+}
+
+// MarshalJSON renders the durations as strings (e.g. "5s")
+func (c otlpExportConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Timeout         string `json:"timeout"`
+		RetryEnabled    bool   `json:"retryEnabled"`
+		InitialInterval string `json:"retryInitialInterval"`
+		MaxInterval     string `json:"retryMaxInterval"`
+		MaxElapsedTime  string `json:"retryMaxElapsedTime"`
+	}{c.Timeout.String(), c.RetryEnabled, c.InitialInterval.String(), c.MaxInterval.String(), c.MaxElapsedTime.String()})
+}
+
+func (c otlpExportConfig) String() string {
+	if !c.RetryEnabled {
+		return fmt.Sprintf("timeout=%s retry=off", c.Timeout)
+	}
+	return fmt.Sprintf("timeout=%s retry initial=%s max=%s max_elapsed=%s",
+		c.Timeout, c.InitialInterval, c.MaxInterval, c.MaxElapsedTime)
+}
+
+// loadOTLPExportConfig reads OTEL_EXPORTER_OTLP_[<SIGNAL>_]TIMEOUT and the
+// OTEL_EXPORTER_OTLP_[<SIGNAL>_]RETRY_* settings (all in milliseconds), with
+// the signal-specific variable winning and the exporter defaults otherwise
+func loadOTLPExportConfig(signal string) otlpExportConfig {
+	millis := func(name string, fallback time.Duration) time.Duration {
+		value, err := strconv.Atoi(envOr("", "OTEL_EXPORTER_OTLP_"+signal+"_"+name, "OTEL_EXPORTER_OTLP_"+name))
+		if err != nil || value < 0 {
+			return fallback
+		}
+		return time.Duration(value) * time.Millisecond
+	}
+	retryEnabled, err := strconv.ParseBool(envOr("", "OTEL_EXPORTER_OTLP_"+signal+"_RETRY_ENABLED", "OTEL_EXPORTER_OTLP_RETRY_ENABLED"))
+	if err != nil {
+		retryEnabled = true
+	}
+
+	cfg := otlpExportConfig{
+		Timeout:         max(millis("TIMEOUT", 10*time.Second), time.Millisecond),
+		RetryEnabled:    retryEnabled,
+		InitialInterval: millis("RETRY_INITIAL_INTERVAL", 5*time.Second),
+		MaxInterval:     millis("RETRY_MAX_INTERVAL", 30*time.Second),
+		MaxElapsedTime:  millis("RETRY_MAX_ELAPSED_TIME", time.Minute),
+	}
+	// Backoff never starts above its ceiling
+	cfg.InitialInterval = min(cfg.InitialInterval, cfg.MaxInterval)
+	return cfg
+}
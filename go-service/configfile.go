@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFilePath is the CONFIG_FILE loaded at startup, if any
+var configFilePath string
+
+// FileConfig is the YAML configuration file layout. Every setting maps onto
+// the environment variable that configures it, so the file is just a
+// structured way to supply the same knobs; values are kept as strings and
+// validated by the code that reads the variable.
+type FileConfig struct {
+	Port          string `yaml:"port"`
+	GRPCPort      string `yaml:"grpcPort"`
+	DownstreamURL string `yaml:"downstreamUrl"`
+	Faults        struct {
+		ErrorRate    string `yaml:"errorRate"`
+		LatencyMs    string `yaml:"latencyMs"`
+		LatencyP99Ms string `yaml:"latencyP99Ms"`
+	} `yaml:"faults"`
+	Sampler struct {
+		Name string `yaml:"name"`
+		Arg  string `yaml:"arg"`
+		// Routes maps an http.route to its own sampling ratio
+		Routes map[string]string `yaml:"routes"`
+	} `yaml:"sampler"`
+	Exporters struct {
+		Traces         string `yaml:"traces"`
+		Metrics        string `yaml:"metrics"`
+		Logs           string `yaml:"logs"`
+		Endpoint       string `yaml:"endpoint"`
+		BackupEndpoint string `yaml:"backupEndpoint"`
+		Protocol       string `yaml:"protocol"`
+	} `yaml:"exporters"`
+	// Env sets any other environment variable by name
+	Env map[string]string `yaml:"env"`
+}
+
+// envSettings flattens the file into environment variable assignments
+func (c FileConfig) envSettings() map[string]string {
+	settings := map[string]string{
+		"PORT":                               c.Port,
+		"GRPC_PORT":                          c.GRPCPort,
+		"DOWNSTREAM_URL":                     c.DownstreamURL,
+		"ERROR_RATE":                         c.Faults.ErrorRate,
+		"LATENCY_MS":                         c.Faults.LatencyMs,
+		"LATENCY_P99_MS":                     c.Faults.LatencyP99Ms,
+		"OTEL_TRACES_SAMPLER":                c.Sampler.Name,
+		"OTEL_TRACES_SAMPLER_ARG":            c.Sampler.Arg,
+		"OTEL_TRACES_EXPORTER":               c.Exporters.Traces,
+		"OTEL_METRICS_EXPORTER":              c.Exporters.Metrics,
+		"OTEL_LOGS_EXPORTER":                 c.Exporters.Logs,
+		"OTEL_EXPORTER_OTLP_ENDPOINT":        c.Exporters.Endpoint,
+		"OTEL_EXPORTER_OTLP_PROTOCOL":        c.Exporters.Protocol,
+		"OTEL_EXPORTER_OTLP_BACKUP_ENDPOINT": c.Exporters.BackupEndpoint,
+	}
+	if len(c.Sampler.Routes) > 0 {
+		var rules []string
+		for _, route := range slices.Sorted(maps.Keys(c.Sampler.Routes)) {
+			rules = append(rules, route+"="+c.Sampler.Routes[route])
+		}
+		settings["OTEL_TRACES_SAMPLER_ROUTES"] = strings.Join(rules, ",")
+	}
+	for key, value := range c.Env {
+		settings[key] = value
+	}
+	return settings
+}
+
+// loadConfigFile reads the YAML file named by CONFIG_FILE and exports its
+// settings as environment variables. Variables already set in the
+// environment take precedence, so a scenario file can be tweaked per run.
+func loadConfigFile() error {
+	configFilePath = os.Getenv("CONFIG_FILE")
+	if configFilePath == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(configFilePath)
+	if err != nil {
+		return err
+	}
+	var cfg FileConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	// Reject unknown keys so a typo doesn't silently leave a default in place
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid config file %s: %w", configFilePath, err)
+	}
+
+	applied, overridden := 0, 0
+	for key, value := range cfg.envSettings() {
+		if value == "" {
+			continue
+		}
+		if _, ok := os.LookupEnv(key); ok {
+			overridden++
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return err
+		}
+		applied++
+	}
+	log.Printf("Loaded config file %s: %d settings applied, %d overridden by environment",
+		configFilePath, applied, overridden)
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// openConn is a connection the API server has accepted and not yet closed
+type openConn struct {
+	state  http.ConnState
+	opened time.Time
+}
+
+// connTracker follows API server connections through http.Server.ConnState,
+// counting them by state (new, active, idle), as they open and close, and by
+// lifetime, so connection churn can be told apart from slow handlers
+type connTracker struct {
+	mu    sync.Mutex
+	conns map[net.Conn]openConn
+
+	open     metric.Int64UpDownCounter
+	opened   metric.Int64Counter
+	closed   metric.Int64Counter
+	duration metric.Float64Histogram
+}
+
+var connections *connTracker
+
+func initConnectionMetrics() error {
+	t := &connTracker{conns: make(map[net.Conn]openConn)}
+	var err error
+	t.open, err = meter.Int64UpDownCounter(
+		"http.server.open_connections",
+		metric.WithDescription("Connections open to the API server, by http.connection.state (new, active, or idle)"),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return err
+	}
+	t.opened, err = meter.Int64Counter(
+		"http.server.connections.opened",
+		metric.WithDescription("Connections accepted by the API server"),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return err
+	}
+	t.closed, err = meter.Int64Counter(
+		"http.server.connections.closed",
+		metric.WithDescription("Connections the API server stopped tracking, by whether they were closed or hijacked (e.g. WebSockets)"),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return err
+	}
+	t.duration, err = meter.Float64Histogram(
+		"http.server.connection.duration",
+		metric.WithDescription("How long API server connections stayed open"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 120, 300),
+	)
+	if err != nil {
+		return err
+	}
+	connections = t
+	return nil
+}
+
+func stateAttr(state http.ConnState) metric.MeasurementOption {
+	return metric.WithAttributes(attribute.String("http.connection.state", state.String()))
+}
+
+// track is the server's ConnState hook
+func (t *connTracker) track(conn net.Conn, state http.ConnState) {
+	ctx := context.Background()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	prev, known := t.conns[conn]
+	if known {
+		t.open.Add(ctx, -1, stateAttr(prev.state))
+	}
+	switch state {
+	case http.StateNew:
+		t.conns[conn] = openConn{state: state, opened: time.Now()}
+		t.opened.Add(ctx, 1)
+		t.open.Add(ctx, 1, stateAttr(state))
+	case http.StateActive, http.StateIdle:
+		if !known {
+			return
+		}
+		t.conns[conn] = openConn{state: state, opened: prev.opened}
+		t.open.Add(ctx, 1, stateAttr(state))
+	case http.StateHijacked, http.StateClosed:
+		if !known {
+			return
+		}
+		delete(t.conns, conn)
+		end := metric.WithAttributes(attribute.String("http.connection.end", state.String()))
+		t.closed.Add(ctx, 1, end)
+		t.duration.Record(ctx, time.Since(prev.opened).Seconds(), end)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/automaxprocs/maxprocs"
+)
+
+// cgroup v2 and v1 CPU controller files
+const (
+	cgroupV2CPUMax   = "/sys/fs/cgroup/cpu.max"
+	cgroupV2CPUStat  = "/sys/fs/cgroup/cpu.stat"
+	cgroupV1Quota    = "/sys/fs/cgroup/cpu/cpu.cfs_quota_us"
+	cgroupV1Period   = "/sys/fs/cgroup/cpu/cpu.cfs_period_us"
+	cgroupV1CPUStat  = "/sys/fs/cgroup/cpu/cpu.stat"
+	microsPerSecond  = 1e6
+	nanosPerSecond   = 1e9
+	unlimitedV2Quota = "max"
+)
+
+// setMaxProcs sizes GOMAXPROCS to the container CPU quota (unless GOMAXPROCS
+// is set explicitly) so the scheduler does not run more threads than the
+// quota allows and get throttled
+func setMaxProcs() {
+	if _, err := maxprocs.Set(maxprocs.Logger(log.Printf)); err != nil {
+		log.Printf("Failed to set GOMAXPROCS from CPU quota: %v", err)
+	}
+}
+
+// readFirstLine returns the trimmed first line of a file
+func readFirstLine(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	line, _, _ := strings.Cut(string(data), "\n")
+	return strings.TrimSpace(line), nil
+}
+
+// cpuQuota returns the cgroup CPU quota in cores, or false when there is no
+// quota or no cgroup CPU controller is visible
+func cpuQuota() (float64, bool) {
+	if line, err := readFirstLine(cgroupV2CPUMax); err == nil {
+		quota, period, _ := strings.Cut(line, " ")
+		if quota == unlimitedV2Quota {
+			return 0, false
+		}
+		q, qErr := strconv.ParseFloat(quota, 64)
+		p, pErr := strconv.ParseFloat(period, 64)
+		if qErr != nil || pErr != nil || p <= 0 {
+			return 0, false
+		}
+		return q / p, true
+	}
+
+	quota, qErr := readFirstLine(cgroupV1Quota)
+	period, pErr := readFirstLine(cgroupV1Period)
+	if qErr != nil || pErr != nil {
+		return 0, false
+	}
+	q, qErr := strconv.ParseFloat(quota, 64)
+	p, pErr := strconv.ParseFloat(period, 64)
+	if qErr != nil || pErr != nil || q <= 0 || p <= 0 {
+		return 0, false
+	}
+	return q / p, true
+}
+
+// cpuThrottled returns the number of throttled periods and total throttled
+// time in seconds from the cgroup cpu.stat file
+func cpuThrottled() (periods int64, seconds float64, ok bool) {
+	path, timeKey, scale := cgroupV2CPUStat, "throttled_usec", microsPerSecond
+	f, err := os.Open(path)
+	if err != nil {
+		path, timeKey, scale = cgroupV1CPUStat, "throttled_time", nanosPerSecond
+		if f, err = os.Open(path); err != nil {
+			return 0, 0, false
+		}
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), " ")
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "nr_throttled":
+			periods = n
+			ok = true
+		case timeKey:
+			seconds = float64(n) / scale
+		}
+	}
+	return periods, seconds, ok
+}
+
+// initCPUMetrics registers a gauge for the container CPU quota and cgroup
+// throttling counters when available. GOMAXPROCS is reported by the runtime
+// instrumentation as go.processor.limit.
+func initCPUMetrics() error {
+	quota, err := meter.Float64ObservableGauge(
+		"container.cpu.quota",
+		metric.WithDescription("CPU quota from the container cgroup, in cores (absent when unlimited)"),
+		metric.WithUnit("{cpu}"),
+	)
+	if err != nil {
+		return err
+	}
+
+	throttledPeriods, err := meter.Int64ObservableCounter(
+		"container.cpu.throttled.periods",
+		metric.WithDescription("Number of cgroup CPU periods in which the container was throttled"),
+		metric.WithUnit("{period}"),
+	)
+	if err != nil {
+		return err
+	}
+
+	throttledTime, err := meter.Float64ObservableCounter(
+		"container.cpu.throttled.time",
+		metric.WithDescription("Total time the container was throttled by its cgroup CPU quota"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		if cores, ok := cpuQuota(); ok {
+			o.ObserveFloat64(quota, cores)
+		}
+		if periods, seconds, ok := cpuThrottled(); ok {
+			o.ObserveInt64(throttledPeriods, periods)
+			o.ObserveFloat64(throttledTime, seconds)
+		}
+		return nil
+	}, quota, throttledPeriods, throttledTime)
+	return err
+}
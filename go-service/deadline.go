@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestTimeoutHeader carries the caller's remaining time budget, in the
+// same format as gRPC's grpc-timeout
+const requestTimeoutHeader = "X-Request-Timeout"
+
+// requestTimeoutMax caps client-supplied timeouts (REQUEST_TIMEOUT_MAX); 0
+// ignores the header entirely
+var requestTimeoutMax time.Duration
+
+var deadlineExceededRequests metric.Int64Counter
+
+func initRequestDeadlines() error {
+	requestTimeoutMax = getEnvDuration("REQUEST_TIMEOUT_MAX", time.Minute)
+
+	var err error
+	deadlineExceededRequests, err = meter.Int64Counter(
+		"http.server.deadline_exceeded_requests",
+		metric.WithDescription("The number of HTTP requests abandoned because the client's X-Request-Timeout elapsed"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return err
+	}
+	if requestTimeoutMax > 0 {
+		log.Printf("Honoring %s up to %s", requestTimeoutHeader, requestTimeoutMax)
+	}
+	return nil
+}
+
+// grpcTimeoutUnits are the unit suffixes of the grpc-timeout format
+var grpcTimeoutUnits = map[byte]time.Duration{
+	'H': time.Hour,
+	'M': time.Minute,
+	'S': time.Second,
+	'm': time.Millisecond,
+	'u': time.Microsecond,
+	'n': time.Nanosecond,
+}
+
+// parseRequestTimeout accepts a grpc-timeout value such as "250m" (250ms) or
+// "2S", a Go duration such as "1.5s", or a bare number of milliseconds. The
+// gRPC form wins where they overlap, so "5m" is five milliseconds.
+func parseRequestTimeout(value string) (time.Duration, error) {
+	if n := len(value); n >= 2 && n <= 9 {
+		if unit, ok := grpcTimeoutUnits[value[n-1]]; ok {
+			if amount, err := strconv.ParseUint(value[:n-1], 10, 64); err == nil {
+				return time.Duration(amount) * unit, nil
+			}
+		}
+	}
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", requestTimeoutHeader, value)
+	}
+	return d, nil
+}
+
+// formatRequestTimeout renders d in grpc-timeout form, in whole milliseconds
+// rounded up so a tiny remaining budget is not sent as zero
+func formatRequestTimeout(d time.Duration) string {
+	ms := (d + time.Millisecond - 1) / time.Millisecond
+	return strconv.FormatInt(int64(ms), 10) + "m"
+}
+
+// withClientDeadline derives the handler's context from X-Request-Timeout,
+// capped at REQUEST_TIMEOUT_MAX, recording the timeout on span. Invalid or
+// non-positive values are noted on the span and otherwise ignored.
+func withClientDeadline(ctx context.Context, span trace.Span, r *http.Request) (context.Context, context.CancelFunc) {
+	value := r.Header.Get(requestTimeoutHeader)
+	if value == "" || requestTimeoutMax <= 0 {
+		return ctx, func() {}
+	}
+	timeout, err := parseRequestTimeout(value)
+	if err == nil && timeout <= 0 {
+		err = fmt.Errorf("non-positive %s %q", requestTimeoutHeader, value)
+	}
+	if err != nil {
+		span.AddEvent("request.timeout.ignored", trace.WithAttributes(attribute.String("error.message", err.Error())))
+		return ctx, func() {}
+	}
+
+	span.SetAttributes(attribute.Int64("request.timeout_ms", timeout.Milliseconds()))
+	if timeout > requestTimeoutMax {
+		timeout = requestTimeoutMax
+		span.SetAttributes(attribute.Bool("request.timeout.capped", true))
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// deadlineExceeded reports whether the handler's context hit the client's
+// deadline, as opposed to the client disconnecting (which ends parent too) or
+// the route's own timeout
+func deadlineExceeded(parent, handler context.Context) bool {
+	return parent.Err() == nil && errors.Is(context.Cause(handler), context.DeadlineExceeded)
+}
+
+// recordDeadlineExceeded marks a request whose client deadline passed before
+// the handler finished
+func recordDeadlineExceeded(ctx context.Context, span trace.Span, method, route string) {
+	span.SetAttributes(attribute.Bool("request.deadline_exceeded", true))
+	deadlineExceededRequests.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("http.request.method", method),
+		attribute.String("http.route", route),
+	))
+}
+
+// deadlineTransport forwards the remaining time budget of the request's
+// context to the callee as X-Request-Timeout, so deadlines shrink rather than
+// reset across hops
+type deadlineTransport struct {
+	next http.RoundTripper
+}
+
+func (t *deadlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	deadline, ok := req.Context().Deadline()
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, context.DeadlineExceeded
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set(requestTimeoutHeader, formatRequestTimeout(remaining))
+	return t.next.RoundTrip(req)
+}
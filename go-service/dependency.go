@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"math/rand"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// dependency is the embedded downstream the compute path calls, nil when
+// DEPENDENCY_ENABLED=false
+var dependency *dependencySimulator
+
+var errDependencyFailed = errors.New("simulated dependency failure")
+
+// dependencySimulator stands in for a downstream service inside this binary:
+// each call is a CLIENT span naming the dependency as its peer, with latency
+// drawn from a lognormal distribution and a configurable failure rate, so a
+// single-binary deployment still produces traces that cross components
+type dependencySimulator struct {
+	name        string
+	mu          float64
+	sigma       float64
+	failureRate float64
+
+	duration metric.Float64Histogram
+}
+
+// newDependencySimulator reads DEPENDENCY_NAME (default pricing-service),
+// DEPENDENCY_LATENCY_MU and DEPENDENCY_LATENCY_SIGMA (lognormal parameters of
+// the latency in milliseconds; defaults 3.0 and 0.5, a median of ~20ms), and
+// DEPENDENCY_FAILURE_RATE (default 0.01)
+func newDependencySimulator() (*dependencySimulator, error) {
+	d := &dependencySimulator{
+		name:        envOr("pricing-service", "DEPENDENCY_NAME"),
+		mu:          getEnvFloat("DEPENDENCY_LATENCY_MU", 3.0),
+		sigma:       max(getEnvFloat("DEPENDENCY_LATENCY_SIGMA", 0.5), 0),
+		failureRate: min(max(getEnvFloat("DEPENDENCY_FAILURE_RATE", 0.01), 0), 1),
+	}
+
+	var err error
+	d.duration, err = meter.Float64Histogram(
+		"dependency.call.duration",
+		metric.WithDescription("Duration of calls to the simulated downstream dependency"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Simulated dependency %s: lognormal latency (mu=%.2f, sigma=%.2f, median %s), failure rate %.3f",
+		d.name, d.mu, d.sigma, d.latency(0), d.failureRate)
+	return d, nil
+}
+
+// latency converts a standard normal draw z into a call latency
+func (d *dependencySimulator) latency(z float64) time.Duration {
+	ms := math.Exp(d.mu + d.sigma*z)
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// Call makes one simulated call to operation under a CLIENT span, returning
+// errDependencyFailed for injected failures or ctx's error if it gives up
+func (d *dependencySimulator) Call(ctx context.Context, operation string) error {
+	ctx, span := tracer.Start(ctx, d.name+"/"+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("peer.service", d.name),
+			attribute.String("server.address", d.name),
+			attribute.String("rpc.system", "simulated"),
+			attribute.String("rpc.service", d.name),
+			attribute.String("rpc.method", operation),
+		),
+	)
+	defer span.End()
+
+	start := time.Now()
+	delay := d.latency(rand.NormFloat64())
+	span.SetAttributes(attribute.Int64("dependency.latency_ms", delay.Milliseconds()))
+
+	err := sleepContext(ctx, delay)
+	if err == nil && rand.Float64() < d.failureRate {
+		err = errDependencyFailed
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String("peer.service", d.name),
+		attribute.String("rpc.method", operation),
+	}
+	if err != nil {
+		errorType := errorTypeDownstream
+		if ctx.Err() != nil {
+			errorType = errorTypeCanceled
+		}
+		setErrorType(ctx, span, errorType)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		attrs = append(attrs, attribute.String("error.type", errorType))
+	}
+	d.duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+	return err
+}
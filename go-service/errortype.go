@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// error.type values classifying why a request failed
+const (
+	errorTypeValidation        = "validation"
+	errorTypeDownstreamTimeout = "downstream_timeout"
+	errorTypeDownstream        = "downstream_error"
+	errorTypeCircuitOpen       = "circuit_open"
+	errorTypeInjected          = "injected"
+	errorTypePanic             = "panic"
+	errorTypeCanceled          = "canceled"
+	errorTypeDeadlineExceeded  = "deadline_exceeded"
+	errorTypeHandlerTimeout    = "handler_timeout"
+	errorTypeRateLimited       = "rate_limited"
+	errorTypeUnauthorized      = "unauthorized"
+)
+
+var errorCount metric.Int64Counter
+
+func initErrorMetrics() error {
+	var err error
+	errorCount, err = meter.Int64Counter(
+		"http.server.errors",
+		metric.WithDescription("The number of failed HTTP requests by error.type"),
+		metric.WithUnit("{request}"),
+	)
+	return err
+}
+
+// errorClass holds the error.type a handler assigned to its request so the
+// tracing middleware can copy it onto the server span and metrics
+type errorClass struct {
+	mu    sync.Mutex
+	value string
+}
+
+type errorClassKey struct{}
+
+func withErrorClass(ctx context.Context) (context.Context, *errorClass) {
+	class := &errorClass{}
+	return context.WithValue(ctx, errorClassKey{}, class), class
+}
+
+func (c *errorClass) get() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.value
+}
+
+// setErrorType classifies the request's failure, tagging span (typically the
+// handler's own span) with error.type. The first classification wins, since
+// later failures are usually consequences of the first.
+func setErrorType(ctx context.Context, span trace.Span, errorType string) {
+	span.SetAttributes(attribute.String("error.type", errorType))
+	class, ok := ctx.Value(errorClassKey{}).(*errorClass)
+	if !ok {
+		return
+	}
+	class.mu.Lock()
+	defer class.mu.Unlock()
+	if class.value == "" {
+		class.value = errorType
+	}
+}
+
+// downstreamErrorType distinguishes downstream calls that timed out or were
+// short-circuited by the circuit breaker from other transport failures
+func downstreamErrorType(err error) string {
+	if errors.Is(err, errCircuitOpen) {
+		return errorTypeCircuitOpen
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return errorTypeDownstreamTimeout
+	}
+	return errorTypeDownstream
+}
+
+// requestErrorType resolves the error.type for a finished request: the
+// handler's classification if any, otherwise one derived from the status.
+// Following HTTP semantic conventions, unclassified 5xx responses use the
+// status code itself and successful requests have no error.type.
+func requestErrorType(classified string, status int) string {
+	if classified != "" {
+		return classified
+	}
+	switch {
+	case status == http.StatusBadRequest:
+		return errorTypeValidation
+	case status == http.StatusUnauthorized:
+		return errorTypeUnauthorized
+	case status == http.StatusTooManyRequests:
+		return errorTypeRateLimited
+	case status == statusClientClosedRequest:
+		return errorTypeCanceled
+	case status >= http.StatusInternalServerError:
+		return strconv.Itoa(status)
+	}
+	return ""
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// http.conditional.result values
+const (
+	conditionalNotModified   = "not_modified"
+	conditionalModified      = "modified"
+	conditionalUnconditional = "unconditional"
+)
+
+var conditionalRequests metric.Int64Counter
+
+func initConditionalMetrics() error {
+	var err error
+	conditionalRequests, err = meter.Int64Counter(
+		"http.server.conditional_requests",
+		metric.WithDescription("GET responses from ETag-enabled routes, by whether If-None-Match let them be answered with 304"),
+		metric.WithUnit("{request}"),
+	)
+	return err
+}
+
+// etagBuffer holds a handler's response until it is complete, so its ETag can
+// be computed before anything is sent
+type etagBuffer struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (b *etagBuffer) WriteHeader(code int) {
+	if b.status == 0 {
+		b.status = code
+	}
+}
+
+func (b *etagBuffer) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+// etagFor is a weak validator over the response body; weak because
+// compression may re-encode the bytes on the way out
+func etagFor(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `W/"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// etagMatches applies If-None-Match's weak comparison: "*" or any listed tag
+// with the same opaque value matches
+func etagMatches(ifNoneMatch, etag string) bool {
+	opaque := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == opaque {
+			return true
+		}
+	}
+	return false
+}
+
+// withETag adds an ETag to next's successful GET responses and answers a
+// matching If-None-Match with 304 Not Modified and no body. The handler still
+// runs, so a 304 saves bandwidth rather than work. Each response is counted
+// by http.conditional.result, which is also set on the server span.
+func withETag(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next(w, r)
+			return
+		}
+
+		buf := &etagBuffer{ResponseWriter: w}
+		next(buf, r)
+		if buf.status == 0 && r.Context().Err() != nil {
+			// Abandoned without a response; leave that to the caller
+			return
+		}
+		if buf.status == 0 {
+			buf.status = http.StatusOK
+		}
+		if buf.status != http.StatusOK {
+			w.WriteHeader(buf.status)
+			w.Write(buf.body.Bytes())
+			return
+		}
+
+		ctx := r.Context()
+		etag := etagFor(buf.body.Bytes())
+		w.Header().Set("ETag", etag)
+
+		result := conditionalUnconditional
+		if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+			result = conditionalModified
+			if etagMatches(ifNoneMatch, etag) {
+				result = conditionalNotModified
+			}
+		}
+		trace.SpanFromContext(ctx).SetAttributes(
+			attribute.String("http.response.etag", etag),
+			attribute.String("http.conditional.result", result),
+		)
+		conditionalRequests.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("http.route", httpRoute(r)),
+			attribute.String("http.conditional.result", result),
+		))
+
+		if result == conditionalNotModified {
+			w.Header().Del("Content-Type")
+			w.Header().Del("Content-Length")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.body.Bytes())
+	}
+}
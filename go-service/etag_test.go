@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestETagAcrossEncodings(t *testing.T) {
+	t.Setenv("COMPRESSION_MIN_BYTES", "16")
+	if err := initCompression(); err != nil {
+		t.Fatal(err)
+	}
+	if err := initConditionalMetrics(); err != nil {
+		t.Fatal(err)
+	}
+	body := strings.Repeat("cacheable ", 100)
+	handler := compressionMiddleware(withETag(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	}))
+	get := func(acceptEncoding, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/compute", nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	identity := get("", "").Header().Get("ETag")
+	if !strings.HasPrefix(identity, `W/"`) {
+		t.Fatalf("ETag = %q, want a weak validator since the bytes vary by Content-Encoding", identity)
+	}
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		ifNoneMatch    string
+		wantStatus     int
+	}{
+		{"gzip shares the weak tag", "gzip", "", http.StatusOK},
+		{"deflate shares the weak tag", "deflate", "", http.StatusOK},
+		{"identity tag revalidates gzip", "gzip", identity, http.StatusNotModified},
+		{"strong form matches weakly", "deflate", strings.TrimPrefix(identity, "W/"), http.StatusNotModified},
+		{"other tag", "gzip", `W/"0000"`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := get(tt.acceptEncoding, tt.ifNoneMatch)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("ETag"); got != identity {
+				t.Errorf("ETag = %q, want %q for every encoding", got, identity)
+			}
+			if tt.wantStatus == http.StatusOK && rec.Header().Get("Content-Encoding") != tt.acceptEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", rec.Header().Get("Content-Encoding"), tt.acceptEncoding)
+			}
+		})
+	}
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutlog"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc"
+)
+
+// exporterNames returns the exporters for a signal from OTEL_<SIGNAL>_EXPORTER
+// (comma-separated, e.g. "otlp,console"), falling back to console when no
+// OTLP endpoint is configured. Inside Kubernetes the default stays otlp to the
+// SDK's default endpoint, so a deployment that relies on it (such as
+// k8s/go-service.yaml) keeps exporting rather than quietly logging to stdout.
+func exporterNames(signal string) []string {
+	if names := splitList(os.Getenv("OTEL_" + signal + "_EXPORTER")); len(names) > 0 {
+		return names
+	}
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_"+signal+"_ENDPOINT") != "" {
+		return []string{"otlp"}
+	}
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return []string{"otlp"}
+	}
+	log.Printf("No OTLP endpoint configured for %s, falling back to console exporter", signal)
+	return []string{"console"}
+}
+
+// additionalOTLPEndpoints returns extra collector URLs from
+// OTEL_EXPORTER_OTLP_ADDITIONAL_ENDPOINTS; the otlp exporter sends to each of
+// them as well as to the standard endpoint
+func additionalOTLPEndpoints() []string {
+	return splitList(os.Getenv("OTEL_EXPORTER_OTLP_ADDITIONAL_ENDPOINTS"))
+}
+
+// otlpSignalEndpoints returns the additional collectors' URLs for signal
+func otlpSignalEndpoints(signal string) []string {
+	var endpoints []string
+	for _, endpoint := range additionalOTLPEndpoints() {
+		endpoints = append(endpoints, otlpSignalURL(signal, endpoint))
+	}
+	return endpoints
+}
+
+// otlpSignalURL turns a collector base URL into the URL the signal's exporter
+// sends to. WithEndpointURL uses the URL's path as the export path, so for
+// http/protobuf the base gets /v1/<signal> appended, as the SDK does for
+// OTEL_EXPORTER_OTLP_ENDPOINT; gRPC endpoints are used as given.
+func otlpSignalURL(signal, base string) string {
+	if otlpProtocol(signal) == "grpc" {
+		return base
+	}
+	return strings.TrimSuffix(base, "/") + "/v1/" + strings.ToLower(signal)
+}
+
+// otlpProtocol returns the OTLP protocol for a signal, preferring the
+// signal-specific OTEL_EXPORTER_OTLP_<SIGNAL>_PROTOCOL over OTEL_EXPORTER_OTLP_PROTOCOL
+func otlpProtocol(signal string) string {
+	if protocol := os.Getenv("OTEL_EXPORTER_OTLP_" + signal + "_PROTOCOL"); protocol != "" {
+		return protocol
+	}
+	if protocol := os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL"); protocol != "" {
+		return protocol
+	}
+	return "http/protobuf"
+}
+
+// otlpInsecure reports whether the OTLP exporter for signal should use a
+// plaintext connection. OTEL_EXPORTER_OTLP_[<SIGNAL>_]INSECURE wins; otherwise
+// an https:// endpoint or a configured CA certificate selects TLS (with the
+// exporter reading OTEL_EXPORTER_OTLP_CERTIFICATE, CLIENT_CERTIFICATE, and
+// CLIENT_KEY itself), and anything else stays plaintext for local collectors.
+func otlpInsecure(signal string) bool {
+	for _, key := range []string{"OTEL_EXPORTER_OTLP_" + signal + "_INSECURE", "OTEL_EXPORTER_OTLP_INSECURE"} {
+		if value, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+			return value
+		}
+	}
+	endpoint := envOr(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "OTEL_EXPORTER_OTLP_"+signal+"_ENDPOINT")
+	if strings.HasPrefix(strings.ToLower(endpoint), "https://") {
+		return false
+	}
+	return envOr("", "OTEL_EXPORTER_OTLP_"+signal+"_CERTIFICATE", "OTEL_EXPORTER_OTLP_CERTIFICATE") == ""
+}
+
+// newTraceExporters creates every trace exporter selected by OTEL_TRACES_EXPORTER
+// (otlp, console, file, or none); spans are fanned out to all of them
+func newTraceExporters(ctx context.Context) ([]sdktrace.SpanExporter, error) {
+	var exporters []sdktrace.SpanExporter
+	for _, name := range exporterNames("TRACES") {
+		switch name {
+		case "otlp":
+			for _, endpoint := range append([]string{""}, otlpSignalEndpoints("TRACES")...) {
+				exporter, err := newOTLPTraceExporter(ctx, endpoint)
+				if err != nil {
+					return nil, err
+				}
+				tracked := &trackedSpanExporter{SpanExporter: exporter, tracker: newExportTracker("traces", endpoint)}
+				if endpoint != "" {
+					exporters = append(exporters, tracked)
+					continue
+				}
+				// The standard endpoint may fail over to a backup collector
+				primary, err := withSpanFailover(ctx, tracked)
+				if err != nil {
+					return nil, err
+				}
+				exporters = append(exporters, primary)
+			}
+		case "console":
+			exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+			if err != nil {
+				return nil, err
+			}
+			exporters = append(exporters, exporter)
+		case "file":
+			exporter, err := newFileTraceExporter(ctx)
+			if err != nil {
+				return nil, err
+			}
+			exporters = append(exporters, exporter)
+		case "none":
+		default:
+			return nil, fmt.Errorf("unsupported traces exporter %q", name)
+		}
+	}
+	return exporters, nil
+}
+
+// newOTLPTraceExporter creates an OTLP span exporter for the configured protocol,
+// with the timeout and retry policy from loadOTLPExportConfig.
+// An empty endpoint uses OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT.
+func newOTLPTraceExporter(ctx context.Context, endpoint string) (sdktrace.SpanExporter, error) {
+	export := loadOTLPExportConfig("TRACES")
+	if endpoint == "" {
+		log.Printf("OTLP traces export: %s", export)
+	}
+	switch protocol := otlpProtocol("TRACES"); protocol {
+	case "grpc":
+		opts := []otlptracegrpc.Option{
+			otlptracegrpc.WithTimeout(export.Timeout),
+			otlptracegrpc.WithRetry(otlptracegrpc.RetryConfig{
+				Enabled:         export.RetryEnabled,
+				InitialInterval: export.InitialInterval,
+				MaxInterval:     export.MaxInterval,
+				MaxElapsedTime:  export.MaxElapsedTime,
+			}),
+			otlptracegrpc.WithDialOption(grpc.WithChainUnaryInterceptor(countExportAttempts)),
+		}
+		if otlpInsecure("TRACES") {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		if endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpointURL(endpoint))
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	case "http/protobuf":
+		opts := []otlptracehttp.Option{
+			otlptracehttp.WithTimeout(export.Timeout),
+			otlptracehttp.WithRetry(otlptracehttp.RetryConfig{
+				Enabled:         export.RetryEnabled,
+				InitialInterval: export.InitialInterval,
+				MaxInterval:     export.MaxInterval,
+				MaxElapsedTime:  export.MaxElapsedTime,
+			}),
+		}
+		if otlpInsecure("TRACES") {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		if endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+		}
+		return otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP traces protocol %q", protocol)
+	}
+}
+
+// temporalitySelector maps OTEL_EXPORTER_OTLP_METRICS_TEMPORALITY_PREFERENCE
+// (cumulative, delta, or lowmemory) to a selector applied to push exporters
+func temporalitySelector() sdkmetric.TemporalitySelector {
+	switch preference := strings.ToLower(os.Getenv("OTEL_EXPORTER_OTLP_METRICS_TEMPORALITY_PREFERENCE")); preference {
+	case "delta":
+		return func(kind sdkmetric.InstrumentKind) metricdata.Temporality {
+			switch kind {
+			case sdkmetric.InstrumentKindCounter, sdkmetric.InstrumentKindHistogram, sdkmetric.InstrumentKindObservableCounter:
+				return metricdata.DeltaTemporality
+			default:
+				return metricdata.CumulativeTemporality
+			}
+		}
+	case "lowmemory":
+		return func(kind sdkmetric.InstrumentKind) metricdata.Temporality {
+			switch kind {
+			case sdkmetric.InstrumentKindCounter, sdkmetric.InstrumentKindHistogram:
+				return metricdata.DeltaTemporality
+			default:
+				return metricdata.CumulativeTemporality
+			}
+		}
+	case "", "cumulative":
+		return sdkmetric.DefaultTemporalitySelector
+	default:
+		log.Printf("Unsupported temporality preference %q, using cumulative", preference)
+		return sdkmetric.DefaultTemporalitySelector
+	}
+}
+
+// newMetricReaders creates a reader for every exporter selected by
+// OTEL_METRICS_EXPORTER. The otlp, console, and file exporters are pushed by periodic
+// readers, while prometheus is a pull-based reader served on /metrics.
+func newMetricReaders(ctx context.Context) ([]sdkmetric.Reader, error) {
+	var readers []sdkmetric.Reader
+	for _, name := range exporterNames("METRICS") {
+		switch name {
+		case "prometheus":
+			reader, err := prometheus.New()
+			if err != nil {
+				return nil, err
+			}
+			readers = append(readers, reader)
+		case "otlp":
+			for _, endpoint := range append([]string{""}, otlpSignalEndpoints("METRICS")...) {
+				exporter, err := newOTLPMetricExporter(ctx, endpoint)
+				if err != nil {
+					return nil, err
+				}
+				tracked := &trackedMetricExporter{Exporter: exporter, tracker: newExportTracker("metrics", endpoint)}
+				if endpoint != "" {
+					readers = append(readers, sdkmetric.NewPeriodicReader(tracked))
+					continue
+				}
+				// The standard endpoint may fail over to a backup collector
+				primary, err := withMetricFailover(ctx, tracked)
+				if err != nil {
+					return nil, err
+				}
+				readers = append(readers, sdkmetric.NewPeriodicReader(primary))
+			}
+		case "console":
+			exporter, err := stdoutmetric.New(
+				stdoutmetric.WithPrettyPrint(),
+				stdoutmetric.WithTemporalitySelector(temporalitySelector()),
+			)
+			if err != nil {
+				return nil, err
+			}
+			readers = append(readers, sdkmetric.NewPeriodicReader(exporter))
+		case "file":
+			exporter, err := newFileMetricExporter(ctx)
+			if err != nil {
+				return nil, err
+			}
+			readers = append(readers, sdkmetric.NewPeriodicReader(exporter))
+		case "none":
+		default:
+			return nil, fmt.Errorf("unsupported metrics exporter %q", name)
+		}
+	}
+	return readers, nil
+}
+
+// newOTLPMetricExporter creates an OTLP metric exporter for the configured protocol,
+// with the timeout and retry policy from loadOTLPExportConfig.
+// An empty endpoint uses OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_METRICS_ENDPOINT.
+func newOTLPMetricExporter(ctx context.Context, endpoint string) (sdkmetric.Exporter, error) {
+	export := loadOTLPExportConfig("METRICS")
+	if endpoint == "" {
+		log.Printf("OTLP metrics export: %s", export)
+	}
+	switch protocol := otlpProtocol("METRICS"); protocol {
+	case "grpc":
+		opts := []otlpmetricgrpc.Option{
+			otlpmetricgrpc.WithTemporalitySelector(temporalitySelector()),
+			otlpmetricgrpc.WithTimeout(export.Timeout),
+			otlpmetricgrpc.WithRetry(otlpmetricgrpc.RetryConfig{
+				Enabled:         export.RetryEnabled,
+				InitialInterval: export.InitialInterval,
+				MaxInterval:     export.MaxInterval,
+				MaxElapsedTime:  export.MaxElapsedTime,
+			}),
+			otlpmetricgrpc.WithDialOption(grpc.WithChainUnaryInterceptor(countExportAttempts)),
+		}
+		if otlpInsecure("METRICS") {
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		}
+		if endpoint != "" {
+			opts = append(opts, otlpmetricgrpc.WithEndpointURL(endpoint))
+		}
+		return otlpmetricgrpc.New(ctx, opts...)
+	case "http/protobuf":
+		opts := []otlpmetrichttp.Option{
+			otlpmetrichttp.WithTemporalitySelector(temporalitySelector()),
+			otlpmetrichttp.WithTimeout(export.Timeout),
+			otlpmetrichttp.WithRetry(otlpmetrichttp.RetryConfig{
+				Enabled:         export.RetryEnabled,
+				InitialInterval: export.InitialInterval,
+				MaxInterval:     export.MaxInterval,
+				MaxElapsedTime:  export.MaxElapsedTime,
+			}),
+		}
+		if otlpInsecure("METRICS") {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		}
+		if endpoint != "" {
+			opts = append(opts, otlpmetrichttp.WithEndpointURL(endpoint))
+		}
+		return otlpmetrichttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP metrics protocol %q", protocol)
+	}
+}
+
+// newLogExporter creates the log exporter selected by OTEL_LOGS_EXPORTER (otlp or console)
+func newLogExporter(ctx context.Context) (sdklog.Exporter, error) {
+	switch name := exporterNames("LOGS")[0]; name {
+	case "otlp":
+		exporter, err := newOTLPLogExporter(ctx, "")
+		if err != nil {
+			return nil, err
+		}
+		return withLogFailover(ctx, &trackedLogExporter{Exporter: exporter, tracker: newExportTracker("logs", "")})
+	case "console":
+		return stdoutlog.New(stdoutlog.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unsupported logs exporter %q", name)
+	}
+}
+
+// newOTLPLogExporter creates an OTLP log exporter for the configured protocol.
+// An empty endpoint uses OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_LOGS_ENDPOINT.
+func newOTLPLogExporter(ctx context.Context, endpoint string) (sdklog.Exporter, error) {
+	switch protocol := otlpProtocol("LOGS"); protocol {
+	case "grpc":
+		opts := []otlploggrpc.Option{otlploggrpc.WithDialOption(grpc.WithChainUnaryInterceptor(countExportAttempts))}
+		if otlpInsecure("LOGS") {
+			opts = append(opts, otlploggrpc.WithInsecure())
+		}
+		if endpoint != "" {
+			opts = append(opts, otlploggrpc.WithEndpointURL(endpoint))
+		}
+		return otlploggrpc.New(ctx, opts...)
+	case "http/protobuf":
+		var opts []otlploghttp.Option
+		if otlpInsecure("LOGS") {
+			opts = append(opts, otlploghttp.WithInsecure())
+		}
+		if endpoint != "" {
+			opts = append(opts, otlploghttp.WithEndpointURL(endpoint))
+		}
+		return otlploghttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP logs protocol %q", protocol)
+	}
+}
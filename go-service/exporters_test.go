@@ -0,0 +1,59 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestOTLPSignalURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		signal   string
+		protocol string
+		base     string
+		want     string
+	}{
+		{"traces", "TRACES", "", "http://collector:4318", "http://collector:4318/v1/traces"},
+		{"metrics", "METRICS", "http/protobuf", "http://collector:4318", "http://collector:4318/v1/metrics"},
+		{"logs", "LOGS", "", "https://collector.example.com", "https://collector.example.com/v1/logs"},
+		{"trailing slash", "TRACES", "", "http://collector:4318/", "http://collector:4318/v1/traces"},
+		{"base path kept", "TRACES", "", "https://gateway.example.com/otlp", "https://gateway.example.com/otlp/v1/traces"},
+		{"grpc as given", "TRACES", "grpc", "http://collector:4317", "http://collector:4317"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", tt.protocol)
+			t.Setenv("OTEL_EXPORTER_OTLP_"+tt.signal+"_PROTOCOL", "")
+			if got := otlpSignalURL(tt.signal, tt.base); got != tt.want {
+				t.Errorf("otlpSignalURL(%q, %q) = %q, want %q", tt.signal, tt.base, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOTLPSignalEndpoints(t *testing.T) {
+	tests := []struct {
+		name           string
+		endpoints      string
+		tracesProtocol string
+		signal         string
+		want           []string
+	}{
+		{"none", "", "", "TRACES", nil},
+		{"each endpoint", "http://a:4318, http://b:4318/", "", "TRACES", []string{"http://a:4318/v1/traces", "http://b:4318/v1/traces"}},
+		{"per-signal path", "http://a:4318", "", "METRICS", []string{"http://a:4318/v1/metrics"}},
+		{"per-signal protocol", "http://a:4317", "grpc", "TRACES", []string{"http://a:4317"}},
+		{"other signal keeps http", "http://a:4318", "grpc", "METRICS", []string{"http://a:4318/v1/metrics"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OTEL_EXPORTER_OTLP_ADDITIONAL_ENDPOINTS", tt.endpoints)
+			t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "")
+			t.Setenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", tt.tracesProtocol)
+			t.Setenv("OTEL_EXPORTER_OTLP_METRICS_PROTOCOL", "")
+			if got := otlpSignalEndpoints(tt.signal); !slices.Equal(got, tt.want) {
+				t.Errorf("otlpSignalEndpoints(%q) = %q, want %q", tt.signal, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// exportTracker remembers the outcome of the most recent exports made by one
+// OTLP exporter, so /readyz can tell a collector that accepts connections
+// from one that actually accepts data
+type exportTracker struct {
+	name   string
+	signal string
+	// standby marks the idle half of a primary/backup failover pair, whose
+	// stale failures don't affect readiness
+	standby atomic.Bool
+	// paired marks either half of a failover pair, whose lost items the
+	// otlpFailover counts since a batch failing here may be resent to the other
+	paired atomic.Bool
+
+	mu          sync.Mutex
+	lastSuccess time.Time
+	lastFailure time.Time
+	lastError   string
+	failedItems int64
+}
+
+// ExportStatus is an exporter's state as reported by /readyz
+type ExportStatus struct {
+	LastSuccess string `json:"lastSuccess,omitempty"`
+	LastFailure string `json:"lastFailure,omitempty"`
+	LastError   string `json:"lastError,omitempty"`
+}
+
+var (
+	exportTrackersMu sync.Mutex
+	exportTrackers   []*exportTracker
+)
+
+// newExportTracker registers a tracker named after the signal and, for
+// additional collectors, the endpoint
+func newExportTracker(signal, endpoint string) *exportTracker {
+	name := signal
+	if endpoint != "" {
+		name += " " + endpoint
+	}
+	t := &exportTracker{name: name, signal: signal}
+
+	exportTrackersMu.Lock()
+	defer exportTrackersMu.Unlock()
+	exportTrackers = append(exportTrackers, t)
+	return t
+}
+
+// record notes the outcome of one export of items, made in attempts requests
+func (t *exportTracker) record(ctx context.Context, items int, attempts int64, err error) {
+	recordExport(ctx, t.signal, items, attempts, err)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err == nil {
+		t.lastSuccess = time.Now()
+		return
+	}
+	t.lastFailure = time.Now()
+	t.lastError = err.Error()
+	if !t.paired.Load() {
+		t.failedItems += int64(items)
+	}
+}
+
+func (t *exportTracker) status() ExportStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var status ExportStatus
+	if !t.lastSuccess.IsZero() {
+		status.LastSuccess = t.lastSuccess.UTC().Format(time.RFC3339)
+	}
+	if !t.lastFailure.IsZero() {
+		status.LastFailure = t.lastFailure.UTC().Format(time.RFC3339)
+		status.LastError = t.lastError
+	}
+	return status
+}
+
+// failing returns an error when the exporter's most recent export failed,
+// unless it is on standby
+func (t *exportTracker) failing() error {
+	if t.standby.Load() {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.lastFailure.IsZero() || t.lastSuccess.After(t.lastFailure) {
+		return nil
+	}
+	if t.lastSuccess.IsZero() {
+		return fmt.Errorf("%s export has never succeeded: %s", t.name, t.lastError)
+	}
+	return fmt.Errorf("%s export failing since %s (last success %s): %s", t.name,
+		t.lastFailure.UTC().Format(time.RFC3339), t.lastSuccess.UTC().Format(time.RFC3339), t.lastError)
+}
+
+// exportStatuses reports every tracked exporter by name
+func exportStatuses() map[string]ExportStatus {
+	exportTrackersMu.Lock()
+	defer exportTrackersMu.Unlock()
+	statuses := make(map[string]ExportStatus, len(exportTrackers))
+	for _, t := range exportTrackers {
+		statuses[t.name] = t.status()
+	}
+	return statuses
+}
+
+// failedExportItems totals, per signal, the items every tracked exporter has
+// failed to export. Behind a failover only a batch that failed on its last
+// attempt counts, not one that was resent successfully.
+func failedExportItems() map[string]int64 {
+	failed := make(map[string]int64)
+	exportTrackersMu.Lock()
+	for _, t := range exportTrackers {
+		t.mu.Lock()
+		failed[t.signal] += t.failedItems
+		t.mu.Unlock()
+	}
+	exportTrackersMu.Unlock()
+
+	otlpFailoversMu.Lock()
+	defer otlpFailoversMu.Unlock()
+	for signal, f := range otlpFailovers {
+		failed[signal] += f.failedItems.Load()
+	}
+	return failed
+}
+
+// exportCheck fails when any OTLP exporter's most recent export failed
+func exportCheck(context.Context) error {
+	exportTrackersMu.Lock()
+	defer exportTrackersMu.Unlock()
+	for _, t := range exportTrackers {
+		if err := t.failing(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type trackedSpanExporter struct {
+	sdktrace.SpanExporter
+	tracker *exportTracker
+}
+
+func (e *trackedSpanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	attemptsCtx, attempts := withExportAttempts(ctx)
+	err := e.SpanExporter.ExportSpans(attemptsCtx, spans)
+	e.tracker.record(ctx, len(spans), attempts.Load(), err)
+	return err
+}
+
+type trackedMetricExporter struct {
+	sdkmetric.Exporter
+	tracker *exportTracker
+}
+
+func (e *trackedMetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	attemptsCtx, attempts := withExportAttempts(ctx)
+	err := e.Exporter.Export(attemptsCtx, rm)
+	e.tracker.record(ctx, metricStreams(rm), attempts.Load(), err)
+	return err
+}
+
+// metricStreams counts the metric streams in one collection
+func metricStreams(rm *metricdata.ResourceMetrics) int {
+	n := 0
+	for _, sm := range rm.ScopeMetrics {
+		n += len(sm.Metrics)
+	}
+	return n
+}
+
+type trackedLogExporter struct {
+	sdklog.Exporter
+	tracker *exportTracker
+}
+
+func (e *trackedLogExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	attemptsCtx, attempts := withExportAttempts(ctx)
+	err := e.Exporter.Export(attemptsCtx, records)
+	e.tracker.record(ctx, len(records), attempts.Load(), err)
+	return err
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxExternalBody caps how much of the external API's response is read and
+// returned to the caller
+const maxExternalBody = 64 << 10
+
+type ExternalResponse struct {
+	Service     string          `json:"service"`
+	Timestamp   string          `json:"timestamp"`
+	URL         string          `json:"url"`
+	PeerService string          `json:"peerService"`
+	Available   bool            `json:"available"`
+	Status      int             `json:"status,omitempty"`
+	DurationMs  int64           `json:"durationMs"`
+	Error       string          `json:"error,omitempty"`
+	Response    json.RawMessage `json:"response,omitempty"`
+}
+
+// externalAPI is the third-party endpoint /api/external calls: EXTERNAL_API_URL,
+// named by EXTERNAL_API_PEER_SERVICE (default: the URL's host) and bounded by
+// EXTERNAL_API_TIMEOUT (default 5s). There is no default URL, so the service
+// never calls out to a third party unless told to.
+type externalAPI struct {
+	url         string
+	peerService string
+	host        string
+	port        int
+	timeout     time.Duration
+}
+
+func loadExternalAPI() (externalAPI, error) {
+	api := externalAPI{
+		url:     os.Getenv("EXTERNAL_API_URL"),
+		timeout: getEnvDuration("EXTERNAL_API_TIMEOUT", 5*time.Second),
+	}
+	u, err := url.Parse(api.url)
+	if err != nil || u.Hostname() == "" {
+		return api, fmt.Errorf("invalid EXTERNAL_API_URL %q", redactURL(api.url))
+	}
+	api.host = u.Hostname()
+	api.port, _ = strconv.Atoi(u.Port())
+	if api.port == 0 {
+		api.port = 80
+		if u.Scheme == "https" {
+			api.port = 443
+		}
+	}
+	api.peerService = envOr(api.host, "EXTERNAL_API_PEER_SERVICE")
+	return api, nil
+}
+
+type peerServiceKey struct{}
+
+// withPeerService names the service outbound calls made with ctx are going to
+func withPeerService(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, peerServiceKey{}, name)
+}
+
+// peerServiceTransport sits beneath the otelhttp transport and tags its CLIENT
+// span with the peer.service set by withPeerService, which otelhttp cannot
+// infer from the URL
+type peerServiceTransport struct {
+	next http.RoundTripper
+}
+
+func (t *peerServiceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if name, ok := req.Context().Value(peerServiceKey{}).(string); ok {
+		trace.SpanFromContext(req.Context()).SetAttributes(attribute.String("peer.service", name))
+	}
+	return t.next.RoundTrip(req)
+}
+
+// externalHandler calls the configured third-party API through the shared
+// instrumented client. The API is optional to this service, so failures are
+// recorded on the external-request span and reported with available=false
+// rather than failing the request. Without EXTERNAL_API_URL it responds 503.
+func externalHandler(w http.ResponseWriter, r *http.Request) {
+	if os.Getenv("EXTERNAL_API_URL") == "" {
+		writeError(w, http.StatusServiceUnavailable, "External API is disabled; set EXTERNAL_API_URL to enable it")
+		return
+	}
+	api, err := loadExternalAPI()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx, span := tracer.Start(r.Context(), "external-request",
+		trace.WithAttributes(
+			attribute.String("peer.service", api.peerService),
+			attribute.String("server.address", api.host),
+			attribute.Int("server.port", api.port),
+			attribute.String("url.full", redactURL(api.url)),
+		),
+	)
+	defer span.End()
+	ctx = withPeerService(ctx, api.peerService)
+	ctx, cancel := context.WithTimeout(ctx, api.timeout)
+	defer cancel()
+
+	response := ExternalResponse{
+		Service:     serviceName,
+		URL:         redactURL(api.url),
+		PeerService: api.peerService,
+	}
+
+	start := time.Now()
+	body, status, err := callExternalAPI(ctx, api.url)
+	elapsed := time.Since(start)
+	recordServerTiming(ctx, "external", elapsed)
+	response.DurationMs = elapsed.Milliseconds()
+	response.Status = status
+
+	switch {
+	case err != nil:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(attribute.String("error.type", downstreamErrorType(err)))
+		logger.WarnContext(ctx, "External API call failed", "peer.service", api.peerService, "error", err)
+		response.Error = err.Error()
+	case status >= http.StatusBadRequest:
+		span.SetStatus(codes.Error, fmt.Sprintf("external API returned HTTP %d", status))
+		span.SetAttributes(attribute.String("error.type", strconv.Itoa(status)))
+		logger.WarnContext(ctx, "External API returned an error", "peer.service", api.peerService, "http.response.status_code", status)
+		response.Error = http.StatusText(status)
+	default:
+		response.Available = true
+	}
+	span.SetAttributes(attribute.Bool("external.available", response.Available))
+
+	if len(body) > 0 {
+		if !json.Valid(body) {
+			body, _ = json.Marshal(string(body))
+		}
+		response.Response = body
+	}
+	response.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// callExternalAPI GETs target, returning up to maxExternalBody bytes of the
+// response along with its status
+func callExternalAPI(ctx context.Context, target string) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxExternalBody))
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+	return body, resp.StatusCode, nil
+}
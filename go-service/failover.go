@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Failover targets, indexing otlpFailover.endpoints and .trackers
+const (
+	failoverPrimary = iota
+	failoverBackup
+)
+
+var failoverTargetNames = [2]string{"primary", "backup"}
+
+// backupOTLPEndpoint returns the collector a signal's otlp exporter fails over
+// to: OTEL_EXPORTER_OTLP_<SIGNAL>_BACKUP_ENDPOINT as given, or else
+// OTEL_EXPORTER_OTLP_BACKUP_ENDPOINT resolved by otlpSignalURL, mirroring how
+// the primary endpoint is resolved
+func backupOTLPEndpoint(signal string) string {
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_" + signal + "_BACKUP_ENDPOINT"); endpoint != "" {
+		return endpoint
+	}
+	base := os.Getenv("OTEL_EXPORTER_OTLP_BACKUP_ENDPOINT")
+	if base == "" {
+		return ""
+	}
+	return otlpSignalURL(signal, base)
+}
+
+var (
+	otlpFailoversMu sync.Mutex
+	// otlpFailovers holds each signal's failover, keyed like exportTracker.signal
+	otlpFailovers = make(map[string]*otlpFailover)
+	failoverCount metric.Int64Counter
+)
+
+// otlpFailover sends a signal's exports to the primary collector until
+// OTEL_EXPORTER_OTLP_FAILOVER_THRESHOLD (default 3) consecutive exports fail,
+// then to the backup, and the same way back again. Once on the backup it
+// retries the primary after OTEL_EXPORTER_OTLP_FAILBACK_INTERVAL (default 5m,
+// 0 stays on the backup). The export that trips the switch is resent to the
+// new target, so a collector outage costs retries rather than data.
+type otlpFailover struct {
+	signal string
+	// endpoints are the two collectors' URLs, redacted for display
+	endpoints [2]string
+	trackers  [2]*exportTracker
+	threshold int
+	failback  time.Duration
+
+	// failedItems counts items whose export failed on every target tried
+	failedItems atomic.Int64
+
+	mu       sync.Mutex
+	active   int
+	failures int
+	since    time.Time
+}
+
+func newOTLPFailover(signal, backup string, trackers [2]*exportTracker) (*otlpFailover, error) {
+	if failoverCount == nil {
+		var err error
+		failoverCount, err = otel.Meter("go-service").Int64Counter(
+			"telemetry.exporter.failovers",
+			metric.WithDescription("Switches between the primary and backup OTLP collectors, by signal, target switched to, and reason"),
+			metric.WithUnit("{failover}"),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	f := &otlpFailover{
+		signal:    signal,
+		endpoints: [2]string{otlpEndpoint(strings.ToUpper(signal), otlpProtocol(strings.ToUpper(signal))), redactURL(backup)},
+		trackers:  trackers,
+		threshold: max(getEnvInt("OTEL_EXPORTER_OTLP_FAILOVER_THRESHOLD", 3), 1),
+		failback:  max(getEnvDuration("OTEL_EXPORTER_OTLP_FAILBACK_INTERVAL", 5*time.Minute), 0),
+		since:     time.Now(),
+	}
+	trackers[failoverBackup].standby.Store(true)
+	trackers[failoverPrimary].paired.Store(true)
+	trackers[failoverBackup].paired.Store(true)
+
+	otlpFailoversMu.Lock()
+	otlpFailovers[signal] = f
+	otlpFailoversMu.Unlock()
+	log.Printf("OTLP %s failover to %s after %d consecutive failed exports", signal, f.endpoints[failoverBackup], f.threshold)
+	return f, nil
+}
+
+// target returns the collector to export to, failing back to the primary
+// once the backup has been active for the failback interval
+func (f *otlpFailover) target(ctx context.Context) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.active == failoverBackup && f.failback > 0 && time.Since(f.since) >= f.failback {
+		f.switchTo(ctx, failoverPrimary, "failback")
+	}
+	return f.active
+}
+
+// result notes the outcome of an export to target, reporting the target to
+// resend to when it tripped a failover
+func (f *otlpFailover) result(ctx context.Context, target int, err error) (int, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if target != f.active {
+		// Another export already switched targets
+		return 0, false
+	}
+	if err == nil {
+		f.failures = 0
+		return 0, false
+	}
+	f.failures++
+	if f.failures < f.threshold {
+		return 0, false
+	}
+	f.switchTo(ctx, 1-target, "export_failures")
+	return f.active, true
+}
+
+// switchTo makes target the active collector and records the switch as a
+// telemetry.exporter.failovers increment and a warning log. f.mu must be held.
+func (f *otlpFailover) switchTo(ctx context.Context, target int, reason string) {
+	from := f.active
+	f.active = target
+	f.failures = 0
+	f.since = time.Now()
+	f.trackers[target].standby.Store(false)
+	f.trackers[from].standby.Store(true)
+
+	recordCtx := context.WithoutCancel(ctx)
+	failoverCount.Add(recordCtx, 1, metric.WithAttributes(
+		attribute.String("telemetry.signal", f.signal),
+		attribute.String("failover.target", failoverTargetNames[target]),
+		attribute.String("failover.reason", reason),
+	))
+	log.Printf("OTLP %s exporter switched from %s to %s collector (%s)", f.signal, failoverTargetNames[from], failoverTargetNames[target], reason)
+	logger.WarnContext(recordCtx, "OTLP exporter failover",
+		"telemetry.signal", f.signal,
+		"failover.from", failoverTargetNames[from],
+		"failover.target", failoverTargetNames[target],
+		"failover.reason", reason,
+		"failover.endpoint", f.endpoints[target],
+	)
+}
+
+// activeEndpoint reports the collector currently receiving exports
+func (f *otlpFailover) activeEndpoint() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.endpoints[f.active]
+}
+
+// export sends one batch of items with send, to the active target and, if
+// that trips a failover, once more to the new target. The items count as lost
+// only when the last attempt fails.
+func (f *otlpFailover) export(ctx context.Context, items int, send func(context.Context, int) error) error {
+	target := f.target(ctx)
+	err := send(ctx, target)
+	if next, switched := f.result(ctx, target, err); switched && ctx.Err() == nil {
+		err = send(ctx, next)
+		f.result(ctx, next, err)
+	}
+	if err != nil {
+		f.failedItems.Add(int64(items))
+	}
+	return err
+}
+
+// activeOTLPEndpoint reports where signal's exports currently go, or "" when
+// it has no backup collector
+func activeOTLPEndpoint(signal string) string {
+	otlpFailoversMu.Lock()
+	f := otlpFailovers[signal]
+	otlpFailoversMu.Unlock()
+	if f == nil {
+		return ""
+	}
+	return f.activeEndpoint()
+}
+
+type failoverSpanExporter struct {
+	exporters [2]sdktrace.SpanExporter
+	failover  *otlpFailover
+}
+
+// withSpanFailover pairs primary with an exporter to the traces backup
+// collector, when one is configured
+func withSpanFailover(ctx context.Context, primary *trackedSpanExporter) (sdktrace.SpanExporter, error) {
+	backup := backupOTLPEndpoint("TRACES")
+	if backup == "" {
+		return primary, nil
+	}
+	exporter, err := newOTLPTraceExporter(ctx, backup)
+	if err != nil {
+		return nil, err
+	}
+	tracked := &trackedSpanExporter{SpanExporter: exporter, tracker: newExportTracker("traces", backup)}
+	failover, err := newOTLPFailover("traces", backup, [2]*exportTracker{primary.tracker, tracked.tracker})
+	if err != nil {
+		return nil, err
+	}
+	return &failoverSpanExporter{exporters: [2]sdktrace.SpanExporter{primary, tracked}, failover: failover}, nil
+}
+
+func (e *failoverSpanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	return e.failover.export(ctx, len(spans), func(ctx context.Context, target int) error {
+		return e.exporters[target].ExportSpans(ctx, spans)
+	})
+}
+
+func (e *failoverSpanExporter) Shutdown(ctx context.Context) error {
+	return errors.Join(e.exporters[failoverPrimary].Shutdown(ctx), e.exporters[failoverBackup].Shutdown(ctx))
+}
+
+// failoverMetricExporter embeds the primary exporter for its temporality and
+// aggregation, which the backup shares
+type failoverMetricExporter struct {
+	sdkmetric.Exporter
+	backup   sdkmetric.Exporter
+	failover *otlpFailover
+}
+
+// withMetricFailover pairs primary with an exporter to the metrics backup
+// collector, when one is configured
+func withMetricFailover(ctx context.Context, primary *trackedMetricExporter) (sdkmetric.Exporter, error) {
+	backup := backupOTLPEndpoint("METRICS")
+	if backup == "" {
+		return primary, nil
+	}
+	exporter, err := newOTLPMetricExporter(ctx, backup)
+	if err != nil {
+		return nil, err
+	}
+	tracked := &trackedMetricExporter{Exporter: exporter, tracker: newExportTracker("metrics", backup)}
+	failover, err := newOTLPFailover("metrics", backup, [2]*exportTracker{primary.tracker, tracked.tracker})
+	if err != nil {
+		return nil, err
+	}
+	return &failoverMetricExporter{Exporter: primary, backup: tracked, failover: failover}, nil
+}
+
+func (e *failoverMetricExporter) target(target int) sdkmetric.Exporter {
+	if target == failoverBackup {
+		return e.backup
+	}
+	return e.Exporter
+}
+
+func (e *failoverMetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	return e.failover.export(ctx, metricStreams(rm), func(ctx context.Context, target int) error {
+		return e.target(target).Export(ctx, rm)
+	})
+}
+
+func (e *failoverMetricExporter) ForceFlush(ctx context.Context) error {
+	return errors.Join(e.Exporter.ForceFlush(ctx), e.backup.ForceFlush(ctx))
+}
+
+func (e *failoverMetricExporter) Shutdown(ctx context.Context) error {
+	return errors.Join(e.Exporter.Shutdown(ctx), e.backup.Shutdown(ctx))
+}
+
+type failoverLogExporter struct {
+	exporters [2]sdklog.Exporter
+	failover  *otlpFailover
+}
+
+// withLogFailover pairs primary with an exporter to the logs backup
+// collector, when one is configured
+func withLogFailover(ctx context.Context, primary *trackedLogExporter) (sdklog.Exporter, error) {
+	backup := backupOTLPEndpoint("LOGS")
+	if backup == "" {
+		return primary, nil
+	}
+	exporter, err := newOTLPLogExporter(ctx, backup)
+	if err != nil {
+		return nil, err
+	}
+	tracked := &trackedLogExporter{Exporter: exporter, tracker: newExportTracker("logs", backup)}
+	failover, err := newOTLPFailover("logs", backup, [2]*exportTracker{primary.tracker, tracked.tracker})
+	if err != nil {
+		return nil, err
+	}
+	return &failoverLogExporter{exporters: [2]sdklog.Exporter{primary, tracked}, failover: failover}, nil
+}
+
+func (e *failoverLogExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	return e.failover.export(ctx, len(records), func(ctx context.Context, target int) error {
+		return e.exporters[target].Export(ctx, records)
+	})
+}
+
+func (e *failoverLogExporter) ForceFlush(ctx context.Context) error {
+	return errors.Join(e.exporters[failoverPrimary].ForceFlush(ctx), e.exporters[failoverBackup].ForceFlush(ctx))
+}
+
+func (e *failoverLogExporter) Shutdown(ctx context.Context) error {
+	return errors.Join(e.exporters[failoverPrimary].Shutdown(ctx), e.exporters[failoverBackup].Shutdown(ctx))
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestBackupOTLPEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		signal   string
+		protocol string
+		backup   string
+		perSig   string
+		want     string
+	}{
+		{"unset", "TRACES", "", "", "", ""},
+		{"traces path appended", "TRACES", "", "http://backup:4318", "", "http://backup:4318/v1/traces"},
+		{"logs path appended", "LOGS", "", "http://backup:4318/", "", "http://backup:4318/v1/logs"},
+		{"grpc as given", "METRICS", "grpc", "http://backup:4317", "", "http://backup:4317"},
+		{"per-signal as given", "TRACES", "", "http://backup:4318", "http://traces-backup:4318/custom", "http://traces-backup:4318/custom"},
+		{"per-signal only", "METRICS", "", "", "http://metrics-backup:4318/v1/metrics", "http://metrics-backup:4318/v1/metrics"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", tt.protocol)
+			t.Setenv("OTEL_EXPORTER_OTLP_"+tt.signal+"_PROTOCOL", "")
+			t.Setenv("OTEL_EXPORTER_OTLP_BACKUP_ENDPOINT", tt.backup)
+			t.Setenv("OTEL_EXPORTER_OTLP_"+tt.signal+"_BACKUP_ENDPOINT", tt.perSig)
+			if got := backupOTLPEndpoint(tt.signal); got != tt.want {
+				t.Errorf("backupOTLPEndpoint(%q) = %q, want %q", tt.signal, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOTLPFailoverExport(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_FAILOVER_THRESHOLD", "2")
+	t.Setenv("OTEL_EXPORTER_OTLP_FAILBACK_INTERVAL", "0")
+	failover, err := newOTLPFailover("test", "http://backup:4318/v1/traces",
+		[2]*exportTracker{newExportTracker("test", "primary"), newExportTracker("test", "backup")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		otlpFailoversMu.Lock()
+		delete(otlpFailovers, "test")
+		otlpFailoversMu.Unlock()
+	})
+
+	errDown := errors.New("collector down")
+	down := [2]bool{true, false}
+	var sent []int
+	send := func(_ context.Context, target int) error {
+		sent = append(sent, target)
+		if down[target] {
+			return errDown
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name     string
+		wantErr  error
+		wantSent []int
+		// wantLost is the running total of items counted as lost
+		wantLost int64
+	}{
+		{"first failure stays on primary", errDown, []int{failoverPrimary}, 10},
+		{"threshold resends to backup", nil, []int{failoverPrimary, failoverBackup}, 10},
+		{"stays on backup", nil, []int{failoverBackup}, 10},
+	}
+	for _, tt := range tests {
+		sent = nil
+		if err := failover.export(context.Background(), 10, send); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: export error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if !slices.Equal(sent, tt.wantSent) {
+			t.Errorf("%s: sent to %v, want %v", tt.name, sent, tt.wantSent)
+		}
+		if lost := failover.failedItems.Load(); lost != tt.wantLost {
+			t.Errorf("%s: %d items counted as lost, want %d", tt.name, lost, tt.wantLost)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxFanout caps the number of concurrent operations per /api/fanout request
+const maxFanout = 50
+
+type FanoutResult struct {
+	Index      int    `json:"index"`
+	DurationMs int    `json:"durationMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+type FanoutResponse struct {
+	Service   string         `json:"service"`
+	Timestamp string         `json:"timestamp"`
+	Succeeded int            `json:"succeeded"`
+	Failed    int            `json:"failed"`
+	Results   []FanoutResult `json:"results"`
+}
+
+// fanoutOperation simulates one internal operation inside its own child span
+func fanoutOperation(ctx context.Context, index int, failureRate float64) FanoutResult {
+	ctx, span := tracer.Start(ctx, "fanout-operation",
+		trace.WithAttributes(attribute.Int("fanout.index", index)),
+	)
+	defer span.End()
+
+	duration := rand.Intn(80) + 10
+	select {
+	case <-time.After(time.Duration(duration) * time.Millisecond):
+	case <-ctx.Done():
+		span.RecordError(ctx.Err())
+		span.SetStatus(codes.Error, ctx.Err().Error())
+		return FanoutResult{Index: index, DurationMs: duration, Error: ctx.Err().Error()}
+	}
+	span.SetAttributes(attribute.Int("fanout.duration_ms", duration))
+
+	if rand.Float64() < failureRate {
+		err := fmt.Errorf("operation %d failed", index)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return FanoutResult{Index: index, DurationMs: duration, Error: err.Error()}
+	}
+
+	return FanoutResult{Index: index, DurationMs: duration}
+}
+
+// fanoutHandler runs ?n= operations (default 5) concurrently, each failing
+// with probability ?failure_rate= (default 0.1). Individual failures are
+// reported in the response rather than failing the whole request.
+func fanoutHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := tracer.Start(ctx, "fanout-request")
+	defer span.End()
+
+	n := 5
+	if value := r.URL.Query().Get("n"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > maxFanout {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("n must be an integer between 1 and %d", maxFanout))
+			return
+		}
+		n = parsed
+	}
+
+	failureRate := 0.1
+	if value := r.URL.Query().Get("failure_rate"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed < 0 || parsed > 1 {
+			writeError(w, http.StatusBadRequest, "failure_rate must be a number between 0 and 1")
+			return
+		}
+		failureRate = parsed
+	}
+
+	results := make([]FanoutResult, n)
+	var g errgroup.Group
+	for i := 0; i < n; i++ {
+		g.Go(func() error {
+			results[i] = fanoutOperation(ctx, i, failureRate)
+			return nil
+		})
+	}
+	g.Wait()
+
+	failed := 0
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+		}
+	}
+	span.SetAttributes(
+		attribute.Int("fanout.count", n),
+		attribute.Int("fanout.failed", failed),
+	)
+	if failed > 0 {
+		span.AddEvent("Partial failure", trace.WithAttributes(attribute.Int("fanout.failed", failed)))
+	}
+
+	response := FanoutResponse{
+		Service:   serviceName,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Succeeded: n - failed,
+		Failed:    failed,
+		Results:   results,
+	}
+
+	// Every operation failing is a server error; partial failure is still a 200
+	w.Header().Set("Content-Type", "application/json")
+	if failed == n {
+		span.SetStatus(codes.Error, "all fanout operations failed")
+		setErrorType(ctx, span, errorTypeInjected)
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	json.NewEncoder(w).Encode(response)
+}
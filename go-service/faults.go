@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// errorRateBits holds the fraction of /api/compute requests that fail with an
+// injected error, stored as float64 bits so it can be changed at runtime
+var errorRateBits atomic.Uint64
+
+// Injected latency: a fixed LATENCY_MS plus an exponentially distributed
+// component whose 99th percentile is LATENCY_P99_MS, stored as durations so
+// they can be changed at runtime
+var (
+	latencyBase atomic.Int64
+	latencyP99  atomic.Int64
+)
+
+func errorRate() float64 {
+	return math.Float64frombits(errorRateBits.Load())
+}
+
+func setErrorRate(rate float64) {
+	errorRateBits.Store(math.Float64bits(rate))
+}
+
+func injectedLatencyConfig() (base, p99 time.Duration) {
+	return time.Duration(latencyBase.Load()), time.Duration(latencyP99.Load())
+}
+
+func setInjectedLatency(base, p99 time.Duration) {
+	latencyBase.Store(int64(base))
+	latencyP99.Store(int64(p99))
+}
+
+// initFaults loads fault injection settings from the environment
+func initFaults() {
+	if value := os.Getenv("ERROR_RATE"); value != "" {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			log.Printf("Invalid ERROR_RATE %q, error injection disabled", value)
+			return
+		}
+		setErrorRate(rate)
+		log.Printf("Error injection enabled at rate %.3f", rate)
+	}
+
+	base := time.Duration(max(getEnvInt("LATENCY_MS", 0), 0)) * time.Millisecond
+	p99 := time.Duration(max(getEnvInt("LATENCY_P99_MS", 0), 0)) * time.Millisecond
+	setInjectedLatency(base, p99)
+	if base > 0 || p99 > 0 {
+		log.Printf("Latency injection enabled (base %s, p99 %s)", base, p99)
+	}
+}
+
+// parseDelay parses a ?delay= value such as "250ms", "1s", or a bare number of milliseconds
+func parseDelay(value string) (time.Duration, error) {
+	if ms, err := strconv.Atoi(value); err == nil {
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// injectedLatency returns the artificial delay for a request. An explicit
+// delay takes precedence over the environment-configured distribution.
+func injectedLatency(delay string) (time.Duration, error) {
+	if delay != "" {
+		d, err := parseDelay(delay)
+		if err != nil || d < 0 {
+			return 0, fmt.Errorf("invalid delay %q", delay)
+		}
+		return d, nil
+	}
+
+	d, p99 := injectedLatencyConfig()
+	if p99 > 0 {
+		// For an exponential distribution p99 = ln(100) * mean
+		mean := float64(p99) / math.Log(100)
+		d += time.Duration(rand.ExpFloat64() * mean)
+	}
+	return d, nil
+}
+
+// sleepWithSpan waits for d inside an "injected-latency" child span, returning
+// early with ctx.Err() if the client goes away
+func sleepWithSpan(ctx context.Context, d time.Duration, source string) error {
+	_, span := tracer.Start(ctx, "injected-latency",
+		trace.WithAttributes(
+			attribute.Int64("latency.injected_ms", d.Milliseconds()),
+			attribute.String("latency.source", source),
+		),
+	)
+	defer span.End()
+
+	if err := sleepContext(ctx, d); err != nil {
+		recordCancellation(ctx, span, "injected-latency")
+		return err
+	}
+	return nil
+}
+
+// shouldInjectError reports whether the current request should fail
+func shouldInjectError() bool {
+	rate := errorRate()
+	return rate > 0 && rand.Float64() < rate
+}
+
+// errorRateHandler reports (GET) or updates (PUT/POST ?rate=0.05) the error injection rate
+func errorRateHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		rate, err := strconv.ParseFloat(r.URL.Query().Get("rate"), 64)
+		if err != nil || rate < 0 || rate > 1 {
+			writeError(w, http.StatusBadRequest, "rate must be a number between 0 and 1")
+			return
+		}
+		setErrorRate(rate)
+		logger.InfoContext(r.Context(), "Error injection rate updated", "error.rate", rate)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]float64{"errorRate": errorRate()})
+}
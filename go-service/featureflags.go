@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/open-feature/go-sdk/openfeature"
+	"github.com/open-feature/go-sdk/openfeature/memprovider"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Feature flags consulted by the service; all default to off
+const (
+	flagSlowPath          = "slow-path"
+	flagNewAlgorithm      = "new-algorithm"
+	flagCardinalityStress = "cardinality-stress"
+)
+
+var knownFlags = []string{flagSlowPath, flagNewAlgorithm, flagCardinalityStress}
+
+var flags *openfeature.Client
+
+// loadFlagValues merges FEATURE_FLAGS_FILE (a JSON object of flag name to
+// bool) with FEATURE_FLAGS (comma-separated names of enabled flags)
+func loadFlagValues() (map[string]bool, error) {
+	values := make(map[string]bool)
+	for _, key := range knownFlags {
+		values[key] = false
+	}
+
+	if path := os.Getenv("FEATURE_FLAGS_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var fromFile map[string]bool
+		if err := json.Unmarshal(data, &fromFile); err != nil {
+			return nil, fmt.Errorf("invalid feature flags file %s: %w", path, err)
+		}
+		for key, enabled := range fromFile {
+			values[key] = enabled
+		}
+	}
+	for _, key := range splitList(os.Getenv("FEATURE_FLAGS")) {
+		values[key] = true
+	}
+	return values, nil
+}
+
+// initFeatureFlags registers an in-memory OpenFeature provider built from the
+// environment, plus a hook emitting evaluation telemetry
+func initFeatureFlags() error {
+	values, err := loadFlagValues()
+	if err != nil {
+		return err
+	}
+
+	inMemory := make(map[string]memprovider.InMemoryFlag, len(values))
+	for key, enabled := range values {
+		variant := "off"
+		if enabled {
+			variant = "on"
+		}
+		inMemory[key] = memprovider.InMemoryFlag{
+			Key:            key,
+			State:          memprovider.Enabled,
+			DefaultVariant: variant,
+			Variants:       map[string]interface{}{"on": true, "off": false},
+		}
+	}
+	if err := openfeature.SetProviderAndWait(memprovider.NewInMemoryProvider(inMemory)); err != nil {
+		return err
+	}
+
+	hook, err := newFlagTelemetryHook()
+	if err != nil {
+		return err
+	}
+	openfeature.AddHooks(hook)
+
+	flags = openfeature.NewClient(serviceName)
+	log.Printf("Feature flags: %v", values)
+	return nil
+}
+
+// flagEnabled evaluates a boolean flag, treating evaluation errors as off
+func flagEnabled(ctx context.Context, key string) bool {
+	enabled, _ := flags.BooleanValue(ctx, key, false, openfeature.EvaluationContext{})
+	return enabled
+}
+
+// flagTelemetryHook records every flag evaluation as a feature_flag span event
+// on the active span and in the feature_flag.evaluations counter
+type flagTelemetryHook struct {
+	openfeature.UnimplementedHook
+	evaluations metric.Int64Counter
+}
+
+func newFlagTelemetryHook() (*flagTelemetryHook, error) {
+	evaluations, err := meter.Int64Counter(
+		"feature_flag.evaluations",
+		metric.WithDescription("The number of feature flag evaluations, by flag, variant, and reason"),
+		metric.WithUnit("{evaluation}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &flagTelemetryHook{evaluations: evaluations}, nil
+}
+
+func (h *flagTelemetryHook) After(ctx context.Context, hookContext openfeature.HookContext,
+	details openfeature.InterfaceEvaluationDetails, _ openfeature.HookHints) error {
+	attrs := []attribute.KeyValue{
+		attribute.String("feature_flag.key", hookContext.FlagKey()),
+		attribute.String("feature_flag.provider_name", hookContext.ProviderMetadata().Name),
+		attribute.String("feature_flag.variant", details.Variant),
+	}
+	trace.SpanFromContext(ctx).AddEvent("feature_flag", trace.WithAttributes(attrs...))
+	h.evaluations.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("feature_flag.key", hookContext.FlagKey()),
+		attribute.String("feature_flag.variant", details.Variant),
+		attribute.String("feature_flag.reason", string(details.Reason)),
+	))
+	return nil
+}
+
+func (h *flagTelemetryHook) Error(ctx context.Context, hookContext openfeature.HookContext, err error, _ openfeature.HookHints) {
+	trace.SpanFromContext(ctx).AddEvent("feature_flag", trace.WithAttributes(
+		attribute.String("feature_flag.key", hookContext.FlagKey()),
+		attribute.String("feature_flag.provider_name", hookContext.ProviderMetadata().Name),
+		attribute.String("error.type", err.Error()),
+	))
+	h.evaluations.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("feature_flag.key", hookContext.FlagKey()),
+		attribute.String("feature_flag.reason", string(openfeature.ErrorReason)),
+	))
+}
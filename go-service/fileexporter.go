@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// rotatingFile is an append-only file that is rotated to <path>.1 .. <path>.N
+// once it grows past maxBytes
+type rotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+func newRotatingFile(path string, maxBytes int64, maxFiles int) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	r := &rotatingFile{path: path, maxBytes: maxBytes, maxFiles: maxFiles}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file = f
+	r.size = info.Size()
+	return nil
+}
+
+// rotate shifts existing backups up by one, dropping the oldest, and starts a new file
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	for i := r.maxFiles - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	if r.maxFiles > 0 {
+		if err := os.Rename(r.path, r.path+".1"); err != nil {
+			return err
+		}
+	} else if err := os.Remove(r.path); err != nil {
+		return err
+	}
+	return r.open()
+}
+
+// WriteLine appends line followed by a newline, rotating first if it would overflow
+func (r *rotatingFile) WriteLine(line []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.size > 0 && r.size+int64(len(line))+1 > r.maxBytes {
+		if err := r.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := r.file.Write(append(line, '\n'))
+	r.size += int64(n)
+	return err
+}
+
+// fileTransport stands in for a collector: it decodes each OTLP/HTTP protobuf
+// export request and writes it to a file as one line of OTLP JSON, so the
+// files can be replayed to a collector later
+type fileTransport struct {
+	out        *rotatingFile
+	newRequest func() proto.Message
+}
+
+func (t *fileTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		if body, err = io.ReadAll(gz); err != nil {
+			return nil, err
+		}
+	}
+
+	msg := t.newRequest()
+	if err := proto.Unmarshal(body, msg); err != nil {
+		return nil, fmt.Errorf("decode export request: %w", err)
+	}
+	line, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.out.WriteLine(line); err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Header:     http.Header{"Content-Type": []string{"application/x-protobuf"}},
+		Body:       io.NopCloser(bytes.NewReader(nil)),
+		Request:    req,
+	}, nil
+}
+
+// fileHTTPClient returns an HTTP client that writes OTLP export requests to
+// <OTEL_EXPORTER_FILE_DIR>/<name>.jsonl, rotated at OTEL_EXPORTER_FILE_MAX_BYTES
+// and keeping OTEL_EXPORTER_FILE_MAX_FILES backups
+func fileHTTPClient(name string, newRequest func() proto.Message) (*http.Client, error) {
+	dir := os.Getenv("OTEL_EXPORTER_FILE_DIR")
+	if dir == "" {
+		dir = "telemetry"
+	}
+	out, err := newRotatingFile(
+		filepath.Join(dir, name+".jsonl"),
+		int64(getEnvInt("OTEL_EXPORTER_FILE_MAX_BYTES", 10<<20)),
+		getEnvInt("OTEL_EXPORTER_FILE_MAX_FILES", 5),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s file: %w", name, err)
+	}
+	return &http.Client{Transport: &fileTransport{out: out, newRequest: newRequest}}, nil
+}
+
+// newFileTraceExporter creates a span exporter writing OTLP JSON lines to traces.jsonl
+func newFileTraceExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
+	client, err := fileHTTPClient("traces", func() proto.Message {
+		return &collectortrace.ExportTraceServiceRequest{}
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The endpoint is never dialed; the transport writes to disk instead
+	return otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpointURL("http://file/v1/traces"),
+		otlptracehttp.WithHTTPClient(client),
+		otlptracehttp.WithCompression(otlptracehttp.NoCompression),
+	)
+}
+
+// newFileMetricExporter creates a metric exporter writing OTLP JSON lines to metrics.jsonl
+func newFileMetricExporter(ctx context.Context) (sdkmetric.Exporter, error) {
+	client, err := fileHTTPClient("metrics", func() proto.Message {
+		return &collectormetrics.ExportMetricsServiceRequest{}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return otlpmetrichttp.New(ctx,
+		otlpmetrichttp.WithEndpointURL("http://file/v1/metrics"),
+		otlpmetrichttp.WithHTTPClient(client),
+		otlpmetrichttp.WithCompression(otlpmetrichttp.NoCompression),
+		otlpmetrichttp.WithTemporalitySelector(temporalitySelector()),
+	)
+}
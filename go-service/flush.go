@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const (
+	defaultFlushTimeout = 10 * time.Second
+	maxFlushTimeout     = time.Minute
+)
+
+// The SDK providers, kept so /internal/flush can force them to export
+var (
+	tracerProvider *sdktrace.TracerProvider
+	meterProvider  *sdkmetric.MeterProvider
+	loggerProvider *sdklog.LoggerProvider
+)
+
+type FlushResult struct {
+	Status     string `json:"status"`
+	DurationMs int64  `json:"durationMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+type FlushResponse struct {
+	Service   string                 `json:"service"`
+	Timestamp string                 `json:"timestamp"`
+	Status    string                 `json:"status"`
+	Signals   map[string]FlushResult `json:"signals"`
+}
+
+// flushHandler force-flushes the trace, metric, and log providers in
+// parallel (POST, optional ?timeout=5s) and reports each signal's outcome,
+// so a scenario can be sure its telemetry left the process before teardown.
+// This request's own span is still open, so it is exported on the next flush.
+func flushHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := tracer.Start(ctx, "internal-flush")
+	defer span.End()
+
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	timeout := defaultFlushTimeout
+	if param := r.URL.Query().Get("timeout"); param != "" {
+		parsed, err := time.ParseDuration(param)
+		if err != nil || parsed <= 0 || parsed > maxFlushTimeout {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("timeout must be a duration between 0 and %s", maxFlushTimeout))
+			return
+		}
+		timeout = parsed
+	}
+	span.SetAttributes(attribute.Int64("flush.timeout_ms", timeout.Milliseconds()))
+
+	// The flush must finish even if the client gives up waiting for it
+	flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	flushers := map[string]func(context.Context) error{
+		"traces":  tracerProvider.ForceFlush,
+		"metrics": meterProvider.ForceFlush,
+		"logs":    loggerProvider.ForceFlush,
+	}
+	response := FlushResponse{
+		Service:   serviceName,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Status:    "ok",
+		Signals:   make(map[string]FlushResult, len(flushers)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for signal, flush := range flushers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := flush(flushCtx)
+			result := FlushResult{Status: "ok", DurationMs: time.Since(start).Milliseconds()}
+			if err != nil {
+				result.Status = "failed"
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			response.Signals[signal] = result
+			if err != nil {
+				response.Status = "failed"
+			}
+		}()
+	}
+	wg.Wait()
+
+	status := http.StatusOK
+	for signal, result := range response.Signals {
+		span.SetAttributes(
+			attribute.String("flush."+signal+".status", result.Status),
+			attribute.Int64("flush."+signal+".duration_ms", result.DurationMs),
+		)
+		if result.Error != "" {
+			span.SetStatus(codes.Error, signal+" flush failed: "+result.Error)
+			status = http.StatusInternalServerError
+		}
+	}
+	logger.InfoContext(ctx, "Telemetry providers flushed", "flush.status", response.Status)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxGraphQLOrders caps the orders(limit:) query
+const maxGraphQLOrders = 100
+
+var graphqlSchema graphql.Schema
+
+// GraphQLRequest is the standard GraphQL-over-HTTP request body
+type GraphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+func (g *GraphQLRequest) validate() []FieldError {
+	if strings.TrimSpace(g.Query) == "" {
+		return []FieldError{{Field: "query", Reason: reasonRequired, Message: "is required"}}
+	}
+	return nil
+}
+
+// tracedResolver runs a resolver inside its own span named after the field,
+// e.g. "graphql.resolve Query.orders"
+func tracedResolver(resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		field := p.Info.ParentType.Name() + "." + p.Info.FieldName
+		path := make([]string, 0)
+		for _, key := range p.Info.Path.AsArray() {
+			path = append(path, fmt.Sprint(key))
+		}
+		ctx, span := tracer.Start(p.Context, "graphql.resolve "+field,
+			trace.WithAttributes(
+				attribute.String("graphql.field.name", p.Info.FieldName),
+				attribute.String("graphql.field.parent_type", p.Info.ParentType.Name()),
+				attribute.String("graphql.field.path", strings.Join(path, ".")),
+			),
+		)
+		defer span.End()
+
+		p.Context = ctx
+		value, err := resolve(p)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return value, err
+	}
+}
+
+func scanOrder(row interface{ Scan(...any) error }) (Order, error) {
+	var order Order
+	err := row.Scan(&order.ID, &order.Item, &order.Quantity, &order.Price, &order.CreatedAt)
+	return order, err
+}
+
+func initGraphQL() error {
+	orderType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Order",
+		Fields: graphql.Fields{
+			"id":        &graphql.Field{Type: graphql.Int},
+			"item":      &graphql.Field{Type: graphql.String},
+			"quantity":  &graphql.Field{Type: graphql.Int},
+			"price":     &graphql.Field{Type: graphql.Float},
+			"createdAt": &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	computeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Compute",
+		Fields: graphql.Fields{
+			"computeTimeMs": &graphql.Field{Type: graphql.Int},
+			"randomValue":   &graphql.Field{Type: graphql.Int},
+			"result":        &graphql.Field{Type: graphql.Float},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"orders": &graphql.Field{
+				Type: graphql.NewList(orderType),
+				Args: graphql.FieldConfigArgument{
+					"limit": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 20},
+				},
+				Resolve: tracedResolver(func(p graphql.ResolveParams) (any, error) {
+					limit, _ := p.Args["limit"].(int)
+					if limit <= 0 || limit > maxGraphQLOrders {
+						return nil, fmt.Errorf("limit must be between 1 and %d", maxGraphQLOrders)
+					}
+					rows, err := db.QueryContext(p.Context,
+						"SELECT id, item, quantity, price, created_at FROM orders ORDER BY id LIMIT ?", limit)
+					if err != nil {
+						return nil, err
+					}
+					defer rows.Close()
+
+					orders := []Order{}
+					for rows.Next() {
+						order, err := scanOrder(rows)
+						if err != nil {
+							return nil, err
+						}
+						orders = append(orders, order)
+					}
+					return orders, rows.Err()
+				}),
+			},
+			"order": &graphql.Field{
+				Type: orderType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: tracedResolver(func(p graphql.ResolveParams) (any, error) {
+					order, err := scanOrder(db.QueryRowContext(p.Context,
+						"SELECT id, item, quantity, price, created_at FROM orders WHERE id = ?", p.Args["id"]))
+					if errors.Is(err, sql.ErrNoRows) {
+						return nil, nil
+					}
+					return order, err
+				}),
+			},
+			"compute": &graphql.Field{
+				Type: computeType,
+				Resolve: tracedResolver(func(p graphql.ResolveParams) (any, error) {
+					response, err := simulateComputation(p.Context, ComputeRequest{})
+					if err != nil {
+						return nil, err
+					}
+					return response, nil
+				}),
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createOrder": &graphql.Field{
+				Type: orderType,
+				Args: graphql.FieldConfigArgument{
+					"item":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"quantity": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"price":    &graphql.ArgumentConfig{Type: graphql.Float, DefaultValue: 0.0},
+				},
+				Resolve: tracedResolver(func(p graphql.ResolveParams) (any, error) {
+					order := Order{
+						Item:      p.Args["item"].(string),
+						Quantity:  p.Args["quantity"].(int),
+						Price:     p.Args["price"].(float64),
+						CreatedAt: time.Now().UTC().Format(time.RFC3339),
+					}
+					if order.Item == "" || order.Quantity <= 0 {
+						return nil, errors.New("order requires item and a positive quantity")
+					}
+					result, err := db.ExecContext(p.Context,
+						"INSERT INTO orders (item, quantity, price, created_at) VALUES (?, ?, ?, ?)",
+						order.Item, order.Quantity, order.Price, order.CreatedAt,
+					)
+					if err != nil {
+						return nil, err
+					}
+					recordRowsAffected(p.Context, result)
+					order.ID, _ = result.LastInsertId()
+					return order, nil
+				}),
+			},
+			"deleteOrder": &graphql.Field{
+				Type: graphql.Boolean,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: tracedResolver(func(p graphql.ResolveParams) (any, error) {
+					result, err := db.ExecContext(p.Context, "DELETE FROM orders WHERE id = ?", p.Args["id"])
+					if err != nil {
+						return nil, err
+					}
+					return recordRowsAffected(p.Context, result) > 0, nil
+				}),
+			},
+		},
+	})
+
+	var err error
+	graphqlSchema, err = graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+	return err
+}
+
+// selectOperation returns the operation in doc that will run: the one named
+// operationName, or the only one when no name is given
+func selectOperation(doc *ast.Document, operationName string) *ast.OperationDefinition {
+	var selected *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" {
+			if selected != nil {
+				return nil
+			}
+			selected = op
+		} else if op.Name != nil && op.Name.Value == operationName {
+			return op
+		}
+	}
+	return selected
+}
+
+// executeGraphQL parses, validates, and executes a request, each phase in its
+// own span under ctx's operation span, which it names after the operation.
+// Documents that fail to parse or validate are never executed and get a 400.
+func executeGraphQL(ctx context.Context, span trace.Span, request GraphQLRequest) (*graphql.Result, int) {
+	_, parseSpan := tracer.Start(ctx, "graphql.parse")
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(request.Query), Name: "GraphQL request"}),
+	})
+	parseSpan.End()
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}, http.StatusBadRequest
+	}
+
+	if op := selectOperation(doc, request.OperationName); op != nil {
+		name := request.OperationName
+		if op.Name != nil {
+			name = op.Name.Value
+		}
+		span.SetAttributes(attribute.String("graphql.operation.type", op.Operation))
+		spanName := op.Operation
+		if name != "" {
+			span.SetAttributes(attribute.String("graphql.operation.name", name))
+			spanName += " " + name
+		}
+		span.SetName(spanName)
+	}
+
+	_, validateSpan := tracer.Start(ctx, "graphql.validate")
+	validation := graphql.ValidateDocument(&graphqlSchema, doc, nil)
+	validateSpan.End()
+	if !validation.IsValid {
+		return &graphql.Result{Errors: validation.Errors}, http.StatusBadRequest
+	}
+
+	execCtx, execSpan := tracer.Start(ctx, "graphql.execute")
+	defer execSpan.End()
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        graphqlSchema,
+		AST:           doc,
+		OperationName: request.OperationName,
+		Args:          request.Variables,
+		Context:       execCtx,
+	})
+	return result, http.StatusOK
+}
+
+// graphqlHandler serves GraphQL over HTTP (POST JSON body, or GET ?query=).
+// Per GraphQL convention, resolver errors are returned in the response body
+// with a 200; they mark the operation span as failed.
+func graphqlHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := tracer.Start(ctx, "GraphQL Operation")
+	defer span.End()
+
+	var request GraphQLRequest
+	switch r.Method {
+	case http.MethodGet:
+		request.Query = r.URL.Query().Get("query")
+		request.OperationName = r.URL.Query().Get("operationName")
+		if variables := r.URL.Query().Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				rejectInvalid(ctx, w, r, []FieldError{{Field: "variables", Reason: reasonMalformed, Message: "must be a JSON object"}})
+				return
+			}
+		}
+	case http.MethodPost:
+		if fields := decodeJSON(w, r, &request); len(fields) > 0 {
+			rejectInvalid(ctx, w, r, fields)
+			return
+		}
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if fields := request.validate(); len(fields) > 0 {
+		rejectInvalid(ctx, w, r, fields)
+		return
+	}
+	span.SetAttributes(attribute.String("graphql.document", request.Query))
+
+	result, status := executeGraphQL(ctx, span, request)
+	if result.HasErrors() {
+		messages := make([]string, len(result.Errors))
+		for i, err := range result.Errors {
+			messages[i] = err.Message
+		}
+		span.SetAttributes(attribute.Int("graphql.errors.count", len(result.Errors)))
+		span.SetStatus(codes.Error, strings.Join(messages, "; "))
+	}
+
+	if status == http.StatusBadRequest {
+		setErrorType(ctx, span, errorTypeValidation)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// computeServiceServer is the server API for goservice.v1.ComputeService
+// (see proto/compute.proto)
+type computeServiceServer interface {
+	Compute(context.Context, *emptypb.Empty) (*structpb.Struct, error)
+}
+
+// computeServiceDesc registers ComputeService without generated code by using
+// well-known protobuf types for the request and response messages
+var computeServiceDesc = grpc.ServiceDesc{
+	ServiceName: "goservice.v1.ComputeService",
+	HandlerType: (*computeServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Compute",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				in := new(emptypb.Empty)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				if interceptor == nil {
+					return srv.(computeServiceServer).Compute(ctx, in)
+				}
+				info := &grpc.UnaryServerInfo{
+					Server:     srv,
+					FullMethod: "/goservice.v1.ComputeService/Compute",
+				}
+				handler := func(ctx context.Context, req any) (any, error) {
+					return srv.(computeServiceServer).Compute(ctx, req.(*emptypb.Empty))
+				}
+				return interceptor(ctx, in, info, handler)
+			},
+		},
+	},
+	Metadata: "proto/compute.proto",
+}
+
+type computeServer struct{}
+
+func (computeServer) Compute(ctx context.Context, _ *emptypb.Empty) (*structpb.Struct, error) {
+	ctx, span := tracer.Start(ctx, "compute-request")
+	defer span.End()
+
+	response, err := simulateComputation(ctx, ComputeRequest{})
+	if ctx.Err() != nil {
+		// gRPC derives the deadline from the caller's grpc-timeout
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			span.SetAttributes(attribute.Bool("request.deadline_exceeded", true))
+		}
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, err.Error())
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+
+	return structpb.NewStruct(map[string]any{
+		"service":       response.Service,
+		"timestamp":     response.Timestamp,
+		"computeTimeMs": response.ComputeTimeMs,
+		"randomValue":   response.RandomValue,
+		"result":        response.Result,
+	})
+}
+
+// startGRPCServer serves ComputeService and the standard gRPC health service
+// on addr, instrumented with the otelgrpc stats handler
+func startGRPCServer(addr string) (*grpc.Server, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	server := grpc.NewServer(grpc.StatsHandler(otelgrpc.NewServerHandler()))
+	server.RegisterService(&computeServiceDesc, computeServer{})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(computeServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	go func() {
+		if err := server.Serve(lis); err != nil {
+			log.Printf("gRPC server stopped: %v", err)
+		}
+	}()
+
+	return server, nil
+}
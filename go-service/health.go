@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// readinessTimeout bounds each individual readiness check
+const readinessTimeout = 2 * time.Second
+
+// queueSaturation is the fraction of queue capacity at which the service
+// reports itself as not ready
+const queueSaturation = 0.9
+
+type ReadinessResponse struct {
+	Status    string            `json:"status"`
+	Service   string            `json:"service"`
+	Timestamp string            `json:"timestamp"`
+	Checks    map[string]string `json:"checks"`
+	Reasons   []string          `json:"reasons,omitempty"`
+	// Exports reports the last successful and failed export of each OTLP exporter
+	Exports map[string]ExportStatus `json:"exports,omitempty"`
+}
+
+// endpointAddress converts an endpoint URL (or bare host:port) into a dialable
+// host:port. When the endpoint has no port, defaultPort is used, or the
+// scheme's well-known port if defaultPort is empty.
+func endpointAddress(endpoint, defaultPort string) (string, error) {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	if u.Port() != "" {
+		return u.Host, nil
+	}
+	if defaultPort == "" {
+		defaultPort = "80"
+		if u.Scheme == "https" {
+			defaultPort = "443"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), defaultPort), nil
+}
+
+// dialCheck reports whether a TCP connection to addr can be established
+func dialCheck(ctx context.Context, addr string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// otlpCheck verifies that each signal exporting over OTLP can reach a
+// collector: its endpoint or, failing that, its backup collector, which the
+// exporter fails over to
+func otlpCheck(ctx context.Context) error {
+	checked := make(map[string]error)
+	dial := func(endpoint, defaultPort string) error {
+		addr, err := endpointAddress(endpoint, defaultPort)
+		if err != nil {
+			return fmt.Errorf("invalid OTLP endpoint %q: %w", endpoint, err)
+		}
+		if err, ok := checked[addr]; ok {
+			return err
+		}
+		if err = dialCheck(ctx, addr); err != nil {
+			err = fmt.Errorf("OTLP endpoint %s unreachable: %w", addr, err)
+		}
+		checked[addr] = err
+		return err
+	}
+
+	for _, signal := range []string{"TRACES", "METRICS", "LOGS"} {
+		if names := splitList(os.Getenv("OTEL_" + signal + "_EXPORTER")); len(names) > 0 && !slices.Contains(names, "otlp") {
+			continue
+		}
+		endpoint := envOr(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "OTEL_EXPORTER_OTLP_"+signal+"_ENDPOINT")
+		if endpoint == "" {
+			continue
+		}
+		defaultPort := "4318"
+		if otlpProtocol(signal) == "grpc" {
+			defaultPort = "4317"
+		}
+
+		if err := dial(endpoint, defaultPort); err != nil {
+			if backup := backupOTLPEndpoint(signal); backup != "" && dial(backup, defaultPort) == nil {
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// downstreamCheck verifies the configured DOWNSTREAM_URL accepts connections
+func downstreamCheck(ctx context.Context) error {
+	addr, err := endpointAddress(os.Getenv("DOWNSTREAM_URL"), "")
+	if err != nil {
+		return fmt.Errorf("invalid DOWNSTREAM_URL: %w", err)
+	}
+	if err := dialCheck(ctx, addr); err != nil {
+		return fmt.Errorf("downstream %s unreachable: %w", addr, err)
+	}
+	return nil
+}
+
+// queueCheck fails when the job queue is close to capacity
+func queueCheck(context.Context) error {
+	depth, capacity := len(queue.jobs), cap(queue.jobs)
+	if float64(depth) >= float64(capacity)*queueSaturation {
+		return fmt.Errorf("job queue saturated (%d/%d)", depth, capacity)
+	}
+	return nil
+}
+
+// readinessChecks returns the checks that apply to the current configuration;
+// endpoints that are not configured are not checked
+func readinessChecks() map[string]func(context.Context) error {
+	checks := map[string]func(context.Context) error{
+		"queue": queueCheck,
+	}
+	if envOr("", "OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT",
+		"OTEL_EXPORTER_OTLP_METRICS_ENDPOINT", "OTEL_EXPORTER_OTLP_LOGS_ENDPOINT") != "" {
+		checks["otlp"] = otlpCheck
+	}
+	if len(exportStatuses()) > 0 {
+		checks["otlp_export"] = exportCheck
+	}
+	if os.Getenv("DOWNSTREAM_URL") != "" {
+		checks["downstream"] = downstreamCheck
+	}
+	return checks
+}
+
+// readyzHandler runs the readiness checks and returns 503 with reasons when
+// any fail, reporting when each OTLP exporter last succeeded
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := tracer.Start(ctx, "readiness-check")
+	defer span.End()
+
+	response := ReadinessResponse{
+		Status:    "ready",
+		Service:   serviceName,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Checks:    map[string]string{},
+		Exports:   exportStatuses(),
+	}
+
+	for name, check := range readinessChecks() {
+		checkCtx, cancel := context.WithTimeout(ctx, readinessTimeout)
+		err := check(checkCtx)
+		cancel()
+
+		if err != nil {
+			response.Checks[name] = "fail"
+			response.Reasons = append(response.Reasons, err.Error())
+		} else {
+			response.Checks[name] = "ok"
+		}
+	}
+	sort.Strings(response.Reasons)
+
+	status := http.StatusOK
+	if len(response.Reasons) > 0 {
+		response.Status = "not ready"
+		status = http.StatusServiceUnavailable
+		span.SetStatus(codes.Error, strings.Join(response.Reasons, "; "))
+	}
+	span.SetAttributes(attribute.Bool("health.ready", status == http.StatusOK))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
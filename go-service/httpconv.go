@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// apiV2Prefix is served with the stable HTTP semantic conventions
+const apiV2Prefix = "/api/v2/"
+
+// httpConventions selects which generation of HTTP semantic conventions a
+// request's spans carry. Routes under /api/v2 emit the stable attribute names
+// (http.request.method, url.path, server.address, ...); every other route
+// keeps the pre-stable names (http.method, http.target, net.host.name, ...),
+// so one service produces both and backends have to handle the mix.
+// Metrics always use the stable names.
+type httpConventions int
+
+const (
+	conventionsLegacy httpConventions = iota
+	conventionsStable
+)
+
+func (c httpConventions) String() string {
+	if c == conventionsStable {
+		return "stable"
+	}
+	return "legacy"
+}
+
+type httpConventionsKey struct{}
+
+// conventionsFor picks the conventions for r from its path
+func conventionsFor(r *http.Request) httpConventions {
+	if strings.HasPrefix(r.URL.Path, apiV2Prefix) {
+		return conventionsStable
+	}
+	return conventionsLegacy
+}
+
+func withHTTPConventions(ctx context.Context, c httpConventions) context.Context {
+	return context.WithValue(ctx, httpConventionsKey{}, c)
+}
+
+// httpConventionsFrom returns the conventions of the request being served
+func httpConventionsFrom(ctx context.Context) httpConventions {
+	c, _ := ctx.Value(httpConventionsKey{}).(httpConventions)
+	return c
+}
+
+// networkProtocolVersion is the HTTP version the request arrived over: "1.1",
+// or "2" whether negotiated by TLS ALPN or spoken as cleartext h2c
+func networkProtocolVersion(r *http.Request) string {
+	if r.ProtoMajor >= 2 {
+		return strconv.Itoa(r.ProtoMajor)
+	}
+	return strconv.Itoa(r.ProtoMajor) + "." + strconv.Itoa(r.ProtoMinor)
+}
+
+// serverRequestAttributes describes an incoming request on its server span
+func (c httpConventions) serverRequestAttributes(r *http.Request, route string) []attribute.KeyValue {
+	host, port := splitHostPort(r.Host)
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	protocol := networkProtocolVersion(r)
+
+	if c == conventionsStable {
+		attrs := []attribute.KeyValue{
+			attribute.String("http.request.method", r.Method),
+			attribute.String("http.route", route),
+			attribute.String("url.path", r.URL.Path),
+			attribute.String("url.scheme", scheme),
+			attribute.String("server.address", host),
+			attribute.String("network.protocol.version", protocol),
+			attribute.String("client.address", clientIP(r)),
+		}
+		if port > 0 {
+			attrs = append(attrs, attribute.Int("server.port", port))
+		}
+		if r.URL.RawQuery != "" {
+			attrs = append(attrs, attribute.String("url.query", r.URL.RawQuery))
+		}
+		if ua := r.UserAgent(); ua != "" {
+			attrs = append(attrs, attribute.String("user_agent.original", ua))
+		}
+		return attrs
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String("http.method", r.Method),
+		attribute.String("http.route", route),
+		attribute.String("http.target", r.URL.RequestURI()),
+		attribute.String("http.scheme", scheme),
+		attribute.String("net.host.name", host),
+		attribute.String("http.flavor", protocol),
+		attribute.String("http.client_ip", clientIP(r)),
+	}
+	if port > 0 {
+		attrs = append(attrs, attribute.Int("net.host.port", port))
+	}
+	if ua := r.UserAgent(); ua != "" {
+		attrs = append(attrs, attribute.String("http.user_agent", ua))
+	}
+	return attrs
+}
+
+// statusCode is the response status code attribute
+func (c httpConventions) statusCode(status int) attribute.KeyValue {
+	if c == conventionsStable {
+		return attribute.Int("http.response.status_code", status)
+	}
+	return attribute.Int("http.status_code", status)
+}
+
+// bodySizes are the request and response body size attributes
+func (c httpConventions) bodySizes(request, response int64) []attribute.KeyValue {
+	if c == conventionsStable {
+		return []attribute.KeyValue{
+			attribute.Int64("http.request.body.size", request),
+			attribute.Int64("http.response.body.size", response),
+		}
+	}
+	return []attribute.KeyValue{
+		attribute.Int64("http.request_content_length", request),
+		attribute.Int64("http.response_content_length", response),
+	}
+}
+
+// handlerAttributes are the request attributes handlers put on their own spans
+func (c httpConventions) handlerAttributes(r *http.Request) []attribute.KeyValue {
+	if c == conventionsStable {
+		return []attribute.KeyValue{
+			attribute.String("http.request.method", r.Method),
+			attribute.String("url.full", requestURL(r)),
+		}
+	}
+	return []attribute.KeyValue{
+		attribute.String("http.method", r.Method),
+		attribute.String("http.url", r.URL.String()),
+	}
+}
+
+// requestURL reconstructs the absolute URL of an incoming request
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+// splitHostPort splits a Host header, returning port 0 when none is given
+func splitHostPort(hostport string) (string, int) {
+	host, portStr, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return hostport, 0
+	}
+	port, _ := strconv.Atoi(portStr)
+	return host, port
+}
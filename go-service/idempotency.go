@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	idempotencyHeader = "Idempotency-Key"
+	// idempotencyReplayedHeader marks a response replayed from the store
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+	// maxIdempotencyKeyLength bounds client-supplied keys
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotency.outcome values
+const (
+	idempotencyHit      = "hit"
+	idempotencyMiss     = "miss"
+	idempotencyInFlight = "in_flight"
+	idempotencyMismatch = "mismatch"
+)
+
+// idempotentResponse is a stored response; done is false while the first
+// request carrying the key is still being handled
+type idempotentResponse struct {
+	fingerprint [sha256.Size]byte
+	done        bool
+	status      int
+	header      http.Header
+	body        []byte
+	expires     time.Time
+}
+
+// claimedKey is a key in the order it was claimed
+type claimedKey struct {
+	key   string
+	entry *idempotentResponse
+}
+
+// idempotencyStore remembers responses to write requests by Idempotency-Key
+// so a retried request gets the original response instead of repeating the
+// write. It holds at most maxKeys entries; since every response lives for the
+// same TTL, claim order is expiry order, so expired and evicted keys are
+// dropped from the front of order without scanning the whole store.
+type idempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]*idempotentResponse
+	order   []claimedKey
+	ttl     time.Duration
+	maxKeys int
+
+	lookups   metric.Int64Counter
+	evictions metric.Int64Counter
+}
+
+var idempotency *idempotencyStore
+
+// initIdempotency enables Idempotency-Key handling, keeping responses for
+// IDEMPOTENCY_TTL (default 10m; 0 disables) and at most IDEMPOTENCY_MAX_KEYS
+// of them (default 10000), evicting the oldest first
+func initIdempotency() error {
+	ttl := getEnvDuration("IDEMPOTENCY_TTL", 10*time.Minute)
+	if ttl <= 0 {
+		return nil
+	}
+
+	s := &idempotencyStore{
+		entries: make(map[string]*idempotentResponse),
+		ttl:     ttl,
+		maxKeys: max(getEnvInt("IDEMPOTENCY_MAX_KEYS", 10000), 1),
+	}
+
+	var err error
+	s.lookups, err = meter.Int64Counter(
+		"idempotency.lookups",
+		metric.WithDescription("The number of write requests carrying an Idempotency-Key, by outcome"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return err
+	}
+	s.evictions, err = meter.Int64Counter(
+		"idempotency.evictions",
+		metric.WithDescription("Idempotency keys dropped before their TTL because the store was full"),
+		metric.WithUnit("{key}"),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.Int64ObservableGauge(
+		"idempotency.keys",
+		metric.WithDescription("The number of idempotency keys currently stored"),
+		metric.WithUnit("{key}"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			o.Observe(int64(len(s.entries)))
+			return nil
+		}),
+	)
+	if err != nil {
+		return err
+	}
+
+	idempotency = s
+	log.Printf("Idempotency-Key support enabled (TTL %s, at most %d keys)", ttl, s.maxKeys)
+	return nil
+}
+
+// begin claims key for a request with the given body fingerprint. It returns
+// the stored entry and the outcome: a miss means the caller must handle the
+// request and then call finish or abandon.
+func (s *idempotencyStore) begin(key string, fingerprint [sha256.Size]byte) (*idempotentResponse, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if entry, ok := s.entries[key]; ok && (!entry.done || now.Before(entry.expires)) {
+		switch {
+		case entry.fingerprint != fingerprint:
+			return entry, idempotencyMismatch
+		case !entry.done:
+			return entry, idempotencyInFlight
+		}
+		return entry, idempotencyHit
+	}
+
+	s.sweep(now)
+	evicted := s.evict()
+	entry := &idempotentResponse{fingerprint: fingerprint}
+	s.entries[key] = entry
+	s.order = append(s.order, claimedKey{key: key, entry: entry})
+	if evicted > 0 {
+		s.evictions.Add(context.Background(), int64(evicted))
+	}
+	return entry, idempotencyMiss
+}
+
+// finish stores entry's response until the TTL expires
+func (s *idempotencyStore) finish(entry *idempotentResponse, status int, header http.Header, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry.status = status
+	entry.header = header
+	entry.body = body
+	entry.expires = time.Now().Add(s.ttl)
+	entry.done = true
+}
+
+// abandon releases key without storing a response, so a retry runs again
+func (s *idempotencyStore) abandon(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+}
+
+// popOldest drops the front of order, reporting whether it was still stored
+// (rather than already abandoned or reclaimed)
+func (s *idempotencyStore) popOldest() (claimedKey, bool) {
+	oldest := s.order[0]
+	s.order[0] = claimedKey{}
+	s.order = s.order[1:]
+	return oldest, s.entries[oldest.key] == oldest.entry
+}
+
+// sweep drops expired responses from the front of order, stopping at the
+// first one still live
+func (s *idempotencyStore) sweep(now time.Time) {
+	for len(s.order) > 0 {
+		oldest := s.order[0]
+		stored := s.entries[oldest.key] == oldest.entry
+		expired := oldest.entry.done && now.After(oldest.entry.expires)
+		if stored && !expired {
+			return
+		}
+		s.popOldest()
+		if stored {
+			delete(s.entries, oldest.key)
+		}
+	}
+}
+
+// evict makes room for one more key by dropping the oldest, returning how
+// many stored keys it dropped
+func (s *idempotencyStore) evict() int {
+	evicted := 0
+	for len(s.entries) >= s.maxKeys && len(s.order) > 0 {
+		if oldest, stored := s.popOldest(); stored {
+			delete(s.entries, oldest.key)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// idempotencyScope identifies whose keys a request's key belongs to: the
+// authenticated principal when there is one, otherwise the tenant. Anonymous
+// requests without a tenant share one scope.
+func idempotencyScope(r *http.Request) string {
+	if id := enduserID(r.Context()); id != "" {
+		return "user:" + id
+	}
+	return "tenant:" + tenantID(r)
+}
+
+// responseCapture passes a response through while keeping a copy to store
+type responseCapture struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (c *responseCapture) WriteHeader(code int) {
+	if c.status == 0 {
+		c.status = code
+	}
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *responseCapture) Write(b []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.body.Write(b)
+	return c.ResponseWriter.Write(b)
+}
+
+func (c *responseCapture) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// idempotencyMiddleware honours Idempotency-Key on POST, PUT, PATCH, and
+// DELETE. The first request with a key runs normally and its response is
+// stored; retries with the same key and body get that response replayed,
+// a reuse with a different body gets 422, and a retry while the original is
+// still running gets 409. Server errors are not stored, so they can be
+// retried. Keys are scoped to the caller (see idempotencyScope), method, and
+// path, so one client's key never replays another's response.
+func idempotencyMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyHeader)
+		if idempotency == nil || key == "" {
+			next(w, r)
+			return
+		}
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			next(w, r)
+			return
+		}
+
+		ctx := r.Context()
+		span := trace.SpanFromContext(ctx)
+		if len(key) > maxIdempotencyKeyLength {
+			rejectInvalid(ctx, w, r, []FieldError{{
+				Field:   idempotencyHeader,
+				Reason:  reasonOutOfRange,
+				Message: "must be at most " + strconv.Itoa(maxIdempotencyKeyLength) + " characters",
+			}})
+			return
+		}
+
+		// Fingerprint the body so a key reused for a different request is caught
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
+		if err != nil {
+			rejectInvalid(ctx, w, r, []FieldError{{Reason: reasonTooLarge, Message: "body must not exceed " + strconv.Itoa(maxRequestBodyBytes) + " bytes"}})
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		storeKey := idempotencyScope(r) + " " + r.Method + " " + r.URL.Path + " " + key
+		entry, outcome := idempotency.begin(storeKey, sha256.Sum256(body))
+		span.SetAttributes(
+			attribute.String("idempotency.key", key),
+			attribute.String("idempotency.outcome", outcome),
+		)
+		idempotency.lookups.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("http.route", httpRoute(r)),
+			attribute.String("idempotency.outcome", outcome),
+		))
+
+		switch outcome {
+		case idempotencyHit:
+			span.AddEvent("idempotency.replayed", trace.WithAttributes(
+				httpConventionsFrom(ctx).statusCode(entry.status),
+			))
+			for name, values := range entry.header {
+				w.Header()[name] = values
+			}
+			w.Header().Set(idempotencyReplayedHeader, "true")
+			w.WriteHeader(entry.status)
+			w.Write(entry.body)
+			return
+		case idempotencyMismatch:
+			writeError(w, http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request")
+			return
+		case idempotencyInFlight:
+			w.Header().Set("Retry-After", "1")
+			writeError(w, http.StatusConflict, "A request with this Idempotency-Key is still being processed")
+			return
+		}
+
+		capture := &responseCapture{ResponseWriter: w}
+		completed := false
+		defer func() {
+			// Release the key if the handler panicked, so the retry runs again
+			if !completed {
+				idempotency.abandon(storeKey)
+			}
+		}()
+		next(capture, r)
+		completed = true
+
+		if capture.status == 0 {
+			capture.status = http.StatusOK
+		}
+		if capture.status >= http.StatusInternalServerError || ctx.Err() != nil {
+			idempotency.abandon(storeKey)
+			return
+		}
+		header := make(http.Header)
+		for _, name := range []string{"Content-Type", "Location"} {
+			if value := w.Header().Get(name); value != "" {
+				header.Set(name, value)
+			}
+		}
+		idempotency.finish(entry, capture.status, header, capture.body.Bytes())
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// idempotentStep is one request sent through idempotencyMiddleware
+type idempotentStep struct {
+	method string
+	path   string
+	key    string
+	tenant string
+	user   string
+	body   string
+
+	wantStatus   int
+	wantReplayed bool
+	// wantBody is the handler's response body, numbered by handler call
+	wantBody string
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	post := func(key, body string) idempotentStep {
+		return idempotentStep{method: http.MethodPost, path: "/api/orders", key: key, body: body}
+	}
+	with := func(s idempotentStep, apply func(*idempotentStep)) idempotentStep {
+		apply(&s)
+		return s
+	}
+	created := func(s idempotentStep, call int, replayed bool) idempotentStep {
+		s.wantStatus, s.wantBody, s.wantReplayed = http.StatusCreated, fmt.Sprintf("order %d", call), replayed
+		return s
+	}
+
+	tests := []struct {
+		name  string
+		steps []idempotentStep
+	}{
+		{"retry is replayed", []idempotentStep{
+			created(post("k1", "a"), 1, false),
+			created(post("k1", "a"), 1, true),
+		}},
+		{"different keys both run", []idempotentStep{
+			created(post("k1", "a"), 1, false),
+			created(post("k2", "a"), 2, false),
+		}},
+		{"reused key with another body", []idempotentStep{
+			created(post("k1", "a"), 1, false),
+			with(post("k1", "b"), func(s *idempotentStep) { s.wantStatus = http.StatusUnprocessableEntity }),
+		}},
+		{"no key", []idempotentStep{
+			created(post("", "a"), 1, false),
+			created(post("", "a"), 2, false),
+		}},
+		{"reads are not stored", []idempotentStep{
+			with(post("k1", ""), func(s *idempotentStep) { s.method, s.wantStatus, s.wantBody = http.MethodGet, http.StatusOK, "order 1" }),
+			with(post("k1", ""), func(s *idempotentStep) { s.method, s.wantStatus, s.wantBody = http.MethodGet, http.StatusOK, "order 2" }),
+		}},
+		{"scoped to path", []idempotentStep{
+			created(post("k1", "a"), 1, false),
+			created(with(post("k1", "a"), func(s *idempotentStep) { s.path = "/api/v2/orders" }), 2, false),
+		}},
+		{"scoped to tenant", []idempotentStep{
+			created(with(post("k1", "a"), func(s *idempotentStep) { s.tenant = "acme" }), 1, false),
+			created(with(post("k1", "a"), func(s *idempotentStep) { s.tenant = "globex" }), 2, false),
+			created(with(post("k1", "a"), func(s *idempotentStep) { s.tenant = "acme" }), 1, true),
+		}},
+		{"scoped to user over tenant", []idempotentStep{
+			created(with(post("k1", "a"), func(s *idempotentStep) { s.user, s.tenant = "alice", "acme" }), 1, false),
+			created(with(post("k1", "a"), func(s *idempotentStep) { s.user, s.tenant = "bob", "acme" }), 2, false),
+			created(with(post("k1", "a"), func(s *idempotentStep) { s.user, s.tenant = "alice", "globex" }), 1, true),
+		}},
+		{"server errors are retried", []idempotentStep{
+			with(post("k1", "fail"), func(s *idempotentStep) { s.wantStatus = http.StatusInternalServerError }),
+			with(post("k1", "fail"), func(s *idempotentStep) { s.wantStatus = http.StatusInternalServerError }),
+			created(post("k1", "a"), 3, false),
+		}},
+		{"oversized key", []idempotentStep{
+			with(post(strings.Repeat("k", maxIdempotencyKeyLength+1), "a"), func(s *idempotentStep) { s.wantStatus = http.StatusBadRequest }),
+		}},
+	}
+	if err := initValidationMetrics(); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useIdempotencyStore(t, "")
+			handler, _ := countingOrderHandler()
+			for i, step := range tt.steps {
+				rec := serveIdempotent(handler, step)
+				if rec.Code != step.wantStatus {
+					t.Fatalf("step %d: status = %d, want %d", i, rec.Code, step.wantStatus)
+				}
+				if replayed := rec.Header().Get(idempotencyReplayedHeader) == "true"; replayed != step.wantReplayed {
+					t.Errorf("step %d: replayed = %t, want %t", i, replayed, step.wantReplayed)
+				}
+				if step.wantBody != "" && rec.Body.String() != step.wantBody {
+					t.Errorf("step %d: body = %q, want %q", i, rec.Body.String(), step.wantBody)
+				}
+			}
+		})
+	}
+}
+
+func TestIdempotencyStoreEviction(t *testing.T) {
+	useIdempotencyStore(t, "2")
+	handler, calls := countingOrderHandler()
+
+	for _, key := range []string{"k1", "k2", "k3"} {
+		serveIdempotent(handler, idempotentStep{method: http.MethodPost, path: "/api/orders", key: key, body: "a"})
+	}
+	if got := len(idempotency.entries); got != 2 {
+		t.Fatalf("store holds %d keys, want 2", got)
+	}
+
+	// k1 was evicted to make room for k3, so it runs again; k3 is replayed
+	serveIdempotent(handler, idempotentStep{method: http.MethodPost, path: "/api/orders", key: "k3", body: "a"})
+	if *calls != 3 {
+		t.Errorf("retrying a stored key ran the handler; calls = %d, want 3", *calls)
+	}
+	serveIdempotent(handler, idempotentStep{method: http.MethodPost, path: "/api/orders", key: "k1", body: "a"})
+	if *calls != 4 {
+		t.Errorf("retrying an evicted key did not run the handler; calls = %d, want 4", *calls)
+	}
+}
+
+// useIdempotencyStore installs a fresh store for the test, optionally capped
+// at maxKeys
+func useIdempotencyStore(t *testing.T, maxKeys string) {
+	t.Helper()
+	t.Setenv("IDEMPOTENCY_TTL", "1m")
+	if maxKeys != "" {
+		t.Setenv("IDEMPOTENCY_MAX_KEYS", maxKeys)
+	}
+	saved := idempotency
+	t.Cleanup(func() { idempotency = saved })
+	if err := initIdempotency(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// countingOrderHandler responds 201 "order <n>" for the nth call, or 500 when
+// the body is "fail"
+func countingOrderHandler() (http.HandlerFunc, *int) {
+	calls := 0
+	return idempotencyMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if body, _ := io.ReadAll(r.Body); string(body) == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		status := http.StatusCreated
+		if r.Method == http.MethodGet {
+			status = http.StatusOK
+		}
+		w.WriteHeader(status)
+		fmt.Fprintf(w, "order %d", calls)
+	}), &calls
+}
+
+func serveIdempotent(handler http.HandlerFunc, step idempotentStep) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+	if step.key != "" {
+		req.Header.Set(idempotencyHeader, step.key)
+	}
+	if step.tenant != "" {
+		req.Header.Set(tenantHeader, step.tenant)
+	}
+	if step.user != "" {
+		req = req.WithContext(context.WithValue(req.Context(), enduserKey{}, step.user))
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"go.opentelemetry.io/contrib/propagators/aws/xray"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// idGeneratorFromEnv returns the trace/span ID generator named by
+// OTEL_TRACES_ID_GENERATOR ("random" or "xray") and the name actually used.
+// A nil generator leaves the SDK's random default in place.
+//
+// "xray" prefixes trace IDs with the start time in epoch seconds, as AWS X-Ray
+// requires, while remaining valid W3C trace IDs.
+func idGeneratorFromEnv() (sdktrace.IDGenerator, string) {
+	switch name := os.Getenv("OTEL_TRACES_ID_GENERATOR"); name {
+	case "", "random":
+		return nil, "random"
+	case "xray":
+		return xray.NewIDGenerator(), "xray"
+	default:
+		log.Printf("Unsupported OTEL_TRACES_ID_GENERATOR %q, using random", name)
+		return nil, "random"
+	}
+}
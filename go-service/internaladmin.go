@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AdminSettings are the runtime-adjustable settings /internal/admin reports
+type AdminSettings struct {
+	Service       string  `json:"service"`
+	Timestamp     string  `json:"timestamp"`
+	LogLevel      string  `json:"logLevel"`
+	Sampler       string  `json:"sampler"`
+	SamplingRatio float64 `json:"samplingRatio"`
+	ErrorRate     float64 `json:"errorRate"`
+	LatencyMs     int64   `json:"latencyMs"`
+	LatencyP99Ms  int64   `json:"latencyP99Ms"`
+}
+
+// AdminUpdate is a partial update; omitted fields are left unchanged
+type AdminUpdate struct {
+	LogLevel      *string  `json:"logLevel"`
+	Sampler       *string  `json:"sampler"`
+	SamplingRatio *float64 `json:"samplingRatio"`
+	ErrorRate     *float64 `json:"errorRate"`
+	LatencyMs     *int64   `json:"latencyMs"`
+	LatencyP99Ms  *int64   `json:"latencyP99Ms"`
+}
+
+func currentAdminSettings() AdminSettings {
+	name, ratio := traceSampler.Config()
+	base, p99 := injectedLatencyConfig()
+	return AdminSettings{
+		Service:       serviceName,
+		Timestamp:     time.Now().UTC().Format(time.RFC3339),
+		LogLevel:      strings.ToLower(logLevel.Level().String()),
+		Sampler:       name,
+		SamplingRatio: ratio,
+		ErrorRate:     errorRate(),
+		LatencyMs:     base.Milliseconds(),
+		LatencyP99Ms:  p99.Milliseconds(),
+	}
+}
+
+// applyAdminUpdate validates every field of u before changing anything, so a
+// bad request leaves the instance as it was. It returns the changed settings
+// as span attributes.
+func applyAdminUpdate(u AdminUpdate) ([]attribute.KeyValue, error) {
+	var level slog.Level
+	if u.LogLevel != nil {
+		if err := level.UnmarshalText([]byte(*u.LogLevel)); err != nil {
+			return nil, fmt.Errorf("logLevel must be one of debug, info, warn, error")
+		}
+	}
+
+	samplerName, ratio := traceSampler.Config()
+	if u.SamplingRatio != nil {
+		if *u.SamplingRatio < 0 || *u.SamplingRatio > 1 {
+			return nil, fmt.Errorf("samplingRatio must be a number between 0 and 1")
+		}
+		ratio = *u.SamplingRatio
+		// A ratio only means something to the ratio-based samplers
+		if !strings.HasSuffix(samplerName, "traceidratio") && samplerName != "error_biased" {
+			samplerName = "parentbased_traceidratio"
+		}
+	}
+	if u.Sampler != nil {
+		samplerName = *u.Sampler
+	}
+	if _, err := newSampler(samplerName, ratio); err != nil {
+		return nil, err
+	}
+
+	if u.ErrorRate != nil && (*u.ErrorRate < 0 || *u.ErrorRate > 1) {
+		return nil, fmt.Errorf("errorRate must be a number between 0 and 1")
+	}
+	base, p99 := injectedLatencyConfig()
+	if u.LatencyMs != nil {
+		base = time.Duration(*u.LatencyMs) * time.Millisecond
+	}
+	if u.LatencyP99Ms != nil {
+		p99 = time.Duration(*u.LatencyP99Ms) * time.Millisecond
+	}
+	if base < 0 || p99 < 0 {
+		return nil, fmt.Errorf("latencyMs and latencyP99Ms must not be negative")
+	}
+
+	var changed []attribute.KeyValue
+	if u.LogLevel != nil {
+		logLevel.Set(level)
+		changed = append(changed, attribute.String("admin.log_level", strings.ToLower(level.String())))
+	}
+	if u.Sampler != nil || u.SamplingRatio != nil {
+		traceSampler.Set(samplerName, ratio)
+		changed = append(changed,
+			attribute.String("admin.sampler", samplerName),
+			attribute.Float64("admin.sampling_ratio", ratio),
+		)
+	}
+	if u.ErrorRate != nil {
+		setErrorRate(*u.ErrorRate)
+		changed = append(changed, attribute.Float64("admin.error_rate", *u.ErrorRate))
+	}
+	if u.LatencyMs != nil || u.LatencyP99Ms != nil {
+		setInjectedLatency(base, p99)
+		changed = append(changed,
+			attribute.Int64("admin.latency_ms", base.Milliseconds()),
+			attribute.Int64("admin.latency_p99_ms", p99.Milliseconds()),
+		)
+	}
+	return changed, nil
+}
+
+// internalAdminHandler reports (GET) or updates (PUT/POST with a JSON
+// AdminUpdate) the log level, sampler, and fault injection settings of the
+// running instance
+func internalAdminHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := tracer.Start(ctx, "internal-admin")
+	defer span.End()
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var update AdminUpdate
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid JSON body")
+			return
+		}
+		changed, err := applyAdminUpdate(update)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		span.AddEvent("admin.settings_changed", trace.WithAttributes(changed...))
+		args := make([]any, 0, len(changed))
+		for _, kv := range changed {
+			args = append(args, slog.Any(string(kv.Key), kv.Value.AsInterface()))
+		}
+		// Warn so the change is logged even if it just raised the log level
+		logger.WarnContext(ctx, "Runtime settings changed via admin API", args...)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(currentAdminSettings())
+}
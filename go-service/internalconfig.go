@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Effective SDK configuration captured at startup for /internal/config
+var (
+	serviceResource        *resource.Resource
+	idGeneratorDescription string
+	propagatorNames        []string
+)
+
+// redacted replaces secret values in the config response
+const redacted = "[REDACTED]"
+
+type SignalConfig struct {
+	Exporters      []string          `json:"exporters"`
+	Protocol       string            `json:"protocol,omitempty"`
+	Endpoint       string            `json:"endpoint,omitempty"`
+	BackupEndpoint string            `json:"backupEndpoint,omitempty"`
+	ActiveEndpoint string            `json:"activeEndpoint,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	Export         *otlpExportConfig `json:"export,omitempty"`
+}
+
+type ConfigResponse struct {
+	Service             string              `json:"service"`
+	ConfigFile          string              `json:"configFile,omitempty"`
+	Build               buildInfo           `json:"build"`
+	Timestamp           string              `json:"timestamp"`
+	Traces              SignalConfig        `json:"traces"`
+	Metrics             SignalConfig        `json:"metrics"`
+	Logs                SignalConfig        `json:"logs"`
+	AdditionalEndpoints []string            `json:"additionalEndpoints,omitempty"`
+	Sampler             string              `json:"sampler"`
+	SamplerRoutes       map[string]float64  `json:"samplerRoutes,omitempty"`
+	IDGenerator         string              `json:"idGenerator"`
+	Propagators         []string            `json:"propagators"`
+	Resource            map[string]string   `json:"resource"`
+	BatchSpanProcessor  bspConfig           `json:"batchSpanProcessor"`
+	SpanLimits          sdktrace.SpanLimits `json:"spanLimits"`
+	MetricTemporality   string              `json:"metricTemporality"`
+	ExemplarFilter      string              `json:"exemplarFilter"`
+}
+
+// envOr returns the first non-empty environment variable among keys, or fallback
+func envOr(fallback string, keys ...string) string {
+	for _, key := range keys {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+	}
+	return fallback
+}
+
+// redactURL hides any credentials embedded in an endpoint URL
+func redactURL(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.User == nil {
+		return endpoint
+	}
+	return u.Redacted()
+}
+
+// otlpEndpoint resolves the endpoint an OTLP exporter for signal sends to,
+// following the signal-specific, generic, then default precedence
+func otlpEndpoint(signal, protocol string) string {
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_" + signal + "_ENDPOINT"); endpoint != "" {
+		return redactURL(endpoint)
+	}
+	base := "http://localhost:4318"
+	if protocol == "grpc" {
+		base = "http://localhost:4317"
+	}
+	base = envOr(base, "OTEL_EXPORTER_OTLP_ENDPOINT")
+	if protocol == "grpc" {
+		return redactURL(base)
+	}
+	return redactURL(strings.TrimSuffix(base, "/") + "/v1/" + strings.ToLower(signal))
+}
+
+// otlpHeaderNames lists configured OTLP header keys with their values redacted,
+// since headers typically carry API keys
+func otlpHeaderNames(signal string) map[string]string {
+	headers := make(map[string]string)
+	for _, key := range []string{"OTEL_EXPORTER_OTLP_HEADERS", "OTEL_EXPORTER_OTLP_" + signal + "_HEADERS"} {
+		for _, pair := range splitList(os.Getenv(key)) {
+			name, _, _ := strings.Cut(pair, "=")
+			headers[strings.TrimSpace(name)] = redacted
+		}
+	}
+	if len(headers) == 0 {
+		return nil
+	}
+	return headers
+}
+
+func signalConfig(signal string, exporters []string) SignalConfig {
+	cfg := SignalConfig{Exporters: exporters}
+	if slices.Contains(exporters, "otlp") {
+		cfg.Protocol = otlpProtocol(signal)
+		cfg.Endpoint = otlpEndpoint(signal, cfg.Protocol)
+		cfg.Headers = otlpHeaderNames(signal)
+		if backup := backupOTLPEndpoint(signal); backup != "" {
+			cfg.BackupEndpoint = redactURL(backup)
+			cfg.ActiveEndpoint = activeOTLPEndpoint(strings.ToLower(signal))
+		}
+		if signal != "LOGS" {
+			export := loadOTLPExportConfig(signal)
+			cfg.Export = &export
+		}
+	}
+	return cfg
+}
+
+// internalConfigHandler reports the OTel configuration this instance resolved
+// at startup, with credentials and header values redacted
+func internalConfigHandler(w http.ResponseWriter, r *http.Request) {
+	_, span := tracer.Start(r.Context(), "internal-config")
+	defer span.End()
+
+	attrs := make(map[string]string)
+	if serviceResource != nil {
+		for _, kv := range serviceResource.Attributes() {
+			attrs[string(kv.Key)] = kv.Value.Emit()
+		}
+	}
+
+	var additional []string
+	for _, endpoint := range additionalOTLPEndpoints() {
+		additional = append(additional, redactURL(endpoint))
+	}
+
+	response := ConfigResponse{
+		Service:             serviceName,
+		ConfigFile:          configFilePath,
+		Build:               build,
+		Timestamp:           time.Now().UTC().Format(time.RFC3339),
+		Traces:              signalConfig("TRACES", exporterNames("TRACES")),
+		Metrics:             signalConfig("METRICS", exporterNames("METRICS")),
+		Logs:                signalConfig("LOGS", exporterNames("LOGS")[:1]),
+		AdditionalEndpoints: additional,
+		Sampler:             traceSampler.Description(),
+		SamplerRoutes:       routeSamplingRules,
+		IDGenerator:         idGeneratorDescription,
+		Propagators:         propagatorNames,
+		Resource:            attrs,
+		BatchSpanProcessor:  loadBSPConfig(),
+		SpanLimits:          spanLimits,
+		MetricTemporality:   envOr("cumulative", "OTEL_EXPORTER_OTLP_METRICS_TEMPORALITY_PREFERENCE"),
+		ExemplarFilter:      envOr("trace_based", "OTEL_METRICS_EXEMPLAR_FILTER"),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
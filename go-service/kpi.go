@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math"
+	"math/rand"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// cowBreeds are the breeds on sale and their base prices in USD; each sale is
+// priced around its breed's base
+var cowBreeds = []struct {
+	name  string
+	price float64
+}{
+	{"holstein", 1800},
+	{"angus", 2400},
+	{"jersey", 1500},
+	{"hereford", 2100},
+}
+
+// herd is the cow inventory every request sells from. When it runs out it is
+// restocked to its full size, so the inventory gauge saws between the two.
+type herd struct {
+	size  int64
+	count atomic.Int64
+}
+
+// sell takes one cow from the herd, reporting whether it had to be restocked
+func (h *herd) sell() bool {
+	for {
+		n := h.count.Load()
+		if n > 0 {
+			if h.count.CompareAndSwap(n, n-1) {
+				return false
+			}
+			continue
+		}
+		if h.count.CompareAndSwap(n, h.size-1) {
+			return true
+		}
+	}
+}
+
+var (
+	cowHerd        *herd
+	cowPriceStdDev float64
+	cowPrice       metric.Float64Histogram
+	cowRevenue     metric.Float64Counter
+	cowRestocks    metric.Int64Counter
+)
+
+// initBusinessMetrics creates the cow sale KPIs alongside cows_sold: a
+// cow_price histogram, a cow_revenue counter, and a cow_inventory gauge over
+// a herd of COW_INVENTORY (default 1000), with sale prices varying by
+// COW_PRICE_STDDEV (default 300) around each breed's base
+func initBusinessMetrics() error {
+	cowHerd = &herd{size: int64(max(getEnvInt("COW_INVENTORY", 1000), 1))}
+	cowHerd.count.Store(cowHerd.size)
+	cowPriceStdDev = max(getEnvFloat("COW_PRICE_STDDEV", 300), 0)
+
+	var err error
+	cowPrice, err = meter.Float64Histogram(
+		"cow_price",
+		metric.WithDescription("Sale price of each cow sold"),
+		metric.WithUnit("{USD}"),
+		metric.WithExplicitBucketBoundaries(500, 1000, 1250, 1500, 1750, 2000, 2250, 2500, 3000, 4000),
+	)
+	if err != nil {
+		return err
+	}
+	cowRevenue, err = meter.Float64Counter(
+		"cow_revenue",
+		metric.WithDescription("Total revenue from cows sold"),
+		metric.WithUnit("{USD}"),
+	)
+	if err != nil {
+		return err
+	}
+	cowRestocks, err = meter.Int64Counter(
+		"cow_restocks",
+		metric.WithDescription("The number of times the herd sold out and was restocked"),
+		metric.WithUnit("{restock}"),
+	)
+	if err != nil {
+		return err
+	}
+	_, err = meter.Int64ObservableGauge(
+		"cow_inventory",
+		metric.WithDescription("Cows left in the herd; decrements with every sale"),
+		metric.WithUnit("{cows}"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(cowHerd.count.Load())
+			return nil
+		}),
+	)
+	if err != nil {
+		return err
+	}
+	log.Printf("Cow herd: %d head, price stddev $%.0f", cowHerd.size, cowPriceStdDev)
+	return nil
+}
+
+// recordCowSale sells one cow per request: it counts the sale on cows_sold,
+// prices it, and records the price, revenue, and breed on the server span
+func recordCowSale(ctx context.Context, method, route string) {
+	attrs := []attribute.KeyValue{
+		attribute.String("http.method", method),
+		attribute.String("http.route", route),
+	}
+	cowsSold.Add(ctx, 1, metric.WithAttributes(attrs...))
+
+	if cowHerd.sell() {
+		cowRestocks.Add(ctx, 1)
+	}
+	breed := cowBreeds[rand.Intn(len(cowBreeds))]
+	price := math.Round(max(breed.price+rand.NormFloat64()*cowPriceStdDev, 100)*100) / 100
+
+	breedAttr := metric.WithAttributes(attribute.String("cow.breed", breed.name))
+	cowPrice.Record(ctx, price, breedAttr)
+	cowRevenue.Add(ctx, price, breedAttr)
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("cow.breed", breed.name),
+		attribute.Float64("cow.price", price),
+	)
+}
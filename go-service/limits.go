@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// limitsOverflow is how far past each configured limit /api/limits goes
+const limitsOverflow = 10
+
+// spanLimits holds the limits the tracer provider was configured with
+var spanLimits sdktrace.SpanLimits
+
+type LimitsResponse struct {
+	Service           string              `json:"service"`
+	Timestamp         string              `json:"timestamp"`
+	Limits            sdktrace.SpanLimits `json:"limits"`
+	AttributesSent    int                 `json:"attributesSent"`
+	ValueLengthSent   int                 `json:"valueLengthSent"`
+	EventsSent        int                 `json:"eventsSent"`
+	LinksSent         int                 `json:"linksSent"`
+	DroppedAttributes int                 `json:"droppedAttributes"`
+	DroppedEvents     int                 `json:"droppedEvents"`
+	DroppedLinks      int                 `json:"droppedLinks"`
+	TraceID           string              `json:"traceId"`
+	SpanID            string              `json:"spanId"`
+}
+
+// overLimit returns a count past limit, or a fixed count when the limit is unlimited (negative)
+func overLimit(limit int) int {
+	if limit < 0 {
+		return limitsOverflow
+	}
+	return limit + limitsOverflow
+}
+
+// limitsHandler creates a span that exceeds every configured span limit so
+// truncation and drop counts can be checked in the backend
+func limitsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	_, span := tracer.Start(ctx, "limits-request")
+	defer span.End()
+
+	// Set the long value first so it is kept (truncated) before the count
+	// limit starts dropping attributes
+	valueLength := overLimit(spanLimits.AttributeValueLengthLimit)
+	span.SetAttributes(attribute.String("limits.long_value", strings.Repeat("x", valueLength)))
+
+	attrCount := overLimit(spanLimits.AttributeCountLimit)
+	for i := 1; i < attrCount; i++ {
+		span.SetAttributes(attribute.Int(fmt.Sprintf("limits.attr_%d", i), i))
+	}
+
+	eventCount := overLimit(spanLimits.EventCountLimit)
+	for i := 0; i < eventCount; i++ {
+		span.AddEvent("limits.event", trace.WithAttributes(attribute.Int("limits.event_index", i)))
+	}
+
+	linkCount := overLimit(spanLimits.LinkCountLimit)
+	for i := 0; i < linkCount; i++ {
+		span.AddLink(trace.LinkFromContext(ctx, attribute.Int("limits.link_index", i)))
+	}
+
+	response := LimitsResponse{
+		Service:         serviceName,
+		Timestamp:       time.Now().UTC().Format(time.RFC3339),
+		Limits:          spanLimits,
+		AttributesSent:  attrCount,
+		EventsSent:      eventCount,
+		LinksSent:       linkCount,
+		ValueLengthSent: valueLength,
+		TraceID:         span.SpanContext().TraceID().String(),
+		SpanID:          span.SpanContext().SpanID().String(),
+	}
+	// The SDK span reports what it dropped; unsampled spans record nothing
+	if ro, ok := span.(sdktrace.ReadOnlySpan); ok {
+		response.DroppedAttributes = ro.DroppedAttributes()
+		response.DroppedEvents = ro.DroppedEvents()
+		response.DroppedLinks = ro.DroppedLinks()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type LinksResponse struct {
+	Service      string `json:"service"`
+	Timestamp    string `json:"timestamp"`
+	RequestTrace string `json:"requestTraceId"`
+	BatchTrace   string `json:"batchTraceId"`
+	LinkedSpanID string `json:"linkedSpanId"`
+}
+
+// linksHandler demonstrates the batch-processing pattern: work is done in a
+// new root span (its own trace) linked back to the request that triggered it
+func linksHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := tracer.Start(ctx, "links-request")
+	defer span.End()
+
+	link := trace.LinkFromContext(ctx, attribute.String("link.reason", "batch-trigger"))
+
+	// Start a new trace for the batch; the link points back to the request span
+	batchCtx, batchSpan := tracer.Start(ctx, "batch-process",
+		trace.WithNewRoot(),
+		trace.WithLinks(link),
+		trace.WithAttributes(attribute.Int("batch.size", 3)),
+	)
+	for i := 0; i < 3; i++ {
+		_, itemSpan := tracer.Start(batchCtx, "batch-item",
+			trace.WithAttributes(attribute.Int("batch.item.index", i)),
+		)
+		time.Sleep(5 * time.Millisecond)
+		itemSpan.End()
+	}
+	batchSpan.End()
+
+	span.SetAttributes(attribute.String("batch.trace_id", batchSpan.SpanContext().TraceID().String()))
+
+	response := LinksResponse{
+		Service:      serviceName,
+		Timestamp:    time.Now().UTC().Format(time.RFC3339),
+		RequestTrace: span.SpanContext().TraceID().String(),
+		BatchTrace:   batchSpan.SpanContext().TraceID().String(),
+		LinkedSpanID: link.SpanContext.SpanID().String(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+)
+
+// logLevel is the minimum level the application logger emits, from LOG_LEVEL
+// (debug, info, warn, error) and adjustable at runtime through /internal/admin
+var logLevel slog.LevelVar
+
+// levelHandler drops records below logLevel before they reach the OTel handler
+// and stamps the rest with the request ID
+type levelHandler struct {
+	slog.Handler
+}
+
+func (h levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= logLevel.Level() && h.Handler.Enabled(ctx, level)
+}
+
+// Handle adds the request ID, when ctx belongs to a request, so logs can be
+// correlated by request ID as well as by trace
+func (h levelHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := requestIDFrom(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return levelHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h levelHandler) WithGroup(name string) slog.Handler {
+	return levelHandler{h.Handler.WithGroup(name)}
+}
+
+// newLevelLogger wraps handler so it honours logLevel, initialised from LOG_LEVEL
+func newLevelLogger(handler slog.Handler) *slog.Logger {
+	if value := os.Getenv("LOG_LEVEL"); value != "" {
+		if err := logLevel.UnmarshalText([]byte(value)); err != nil {
+			log.Printf("Invalid LOG_LEVEL %q, using info", value)
+		}
+	}
+	return slog.New(levelHandler{handler})
+}
@@ -1,33 +1,57 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"math"
 	"math/rand"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
+	"go.opentelemetry.io/contrib/bridges/otelslog"
+	"go.opentelemetry.io/contrib/instrumentation/host"
+	"go.opentelemetry.io/contrib/instrumentation/runtime"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/log/global"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/propagation"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/exemplar"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
 	"go.opentelemetry.io/otel/trace"
 )
 
 var (
-	tracer       trace.Tracer
-	meter        metric.Meter
-	cowsSold     metric.Int64Counter
-	requestCount metric.Int64Counter
+	tracer          trace.Tracer
+	meter           metric.Meter
+	logger          *slog.Logger
+	cowsSold        metric.Int64Counter
+	requestCount    metric.Int64Counter
+	requestDuration metric.Float64Histogram
+	activeRequests  metric.Int64UpDownCounter
+
+	requestBodySize  metric.Int64Histogram
+	responseBodySize metric.Int64Histogram
+
+	cache         *computeCache
+	cacheKeyspace int
+
+	queue *jobQueue
 )
 
 type HealthResponse struct {
@@ -40,88 +64,167 @@ type ComputeResponse struct {
 	Service       string  `json:"service"`
 	Timestamp     string  `json:"timestamp"`
 	ComputeTimeMs int     `json:"computeTimeMs"`
+	Iterations    int     `json:"iterations"`
 	RandomValue   int     `json:"randomValue"`
 	Result        float64 `json:"result"`
 }
 
-type ErrorResponse struct {
-	Error     string `json:"error"`
-	Service   string `json:"service"`
-	Timestamp string `json:"timestamp"`
+// samplerFromEnv builds a sampler from OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG,
+// defaulting to parentbased_always_on
+func samplerFromEnv() *dynamicSampler {
+	ratio := 1.0
+	if arg := os.Getenv("OTEL_TRACES_SAMPLER_ARG"); arg != "" {
+		parsed, err := strconv.ParseFloat(arg, 64)
+		if err != nil || parsed < 0 || parsed > 1 {
+			log.Printf("Invalid OTEL_TRACES_SAMPLER_ARG %q, using 1.0", arg)
+		} else {
+			ratio = parsed
+		}
+	}
+
+	name := os.Getenv("OTEL_TRACES_SAMPLER")
+	if name == "" {
+		name = "parentbased_always_on"
+	}
+	s := &dynamicSampler{}
+	if err := s.Set(name, ratio); err != nil {
+		log.Printf("Unsupported OTEL_TRACES_SAMPLER %q, using parentbased_always_on", name)
+		s.Set("parentbased_always_on", ratio)
+	}
+	return s
 }
 
-func initTracer() (*sdktrace.TracerProvider, error) {
+func initTracer(res *resource.Resource) (*sdktrace.TracerProvider, error) {
 	ctx := context.Background()
 
-	// Create OTLP HTTP exporter
-	// Will use OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT env var
-	exporter, err := otlptracehttp.New(ctx,
-		otlptracehttp.WithInsecure(),
-	)
+	// Create span exporters (OTLP and/or console per OTEL_TRACES_EXPORTER)
+	exporters, err := newTraceExporters(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
 	}
 
-	// Create resource
-	res, err := resource.New(ctx,
-		resource.WithAttributes(
-			semconv.ServiceName("go-service"),
-			semconv.ServiceVersion("1.0.0"),
-		),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create resource: %w", err)
+	// Create tracer provider
+	traceSampler = samplerFromEnv()
+	log.Printf("Using trace sampler: %s", traceSampler.Description())
+	routeSamplingRules = loadRouteSamplingRules()
+	if len(routeSamplingRules) > 0 {
+		log.Printf("Per-route sampling ratios: %v", routeSamplingRules)
 	}
 
-	// Create tracer provider
-	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(exporter),
+	bsp := loadBSPConfig()
+	log.Printf("Batch span processor: queue=%d batch=%d timeout=%s delay=%s",
+		bsp.MaxQueueSize, bsp.MaxExportBatchSize, bsp.ExportTimeout, bsp.ScheduleDelay)
+
+	// Span limits come from OTEL_SPAN_*_LIMIT / OTEL_ATTRIBUTE_*_LIMIT
+	spanLimits = sdktrace.NewSpanLimits()
+	log.Printf("Span limits: attributes=%d value_length=%d events=%d links=%d",
+		spanLimits.AttributeCountLimit, spanLimits.AttributeValueLengthLimit,
+		spanLimits.EventCountLimit, spanLimits.LinkCountLimit)
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithSpanProcessor(newGlobalAttributesProcessor()),
 		sdktrace.WithResource(res),
-	)
+		sdktrace.WithSampler(newRouteSampler(traceSampler, routeSamplingRules)),
+		sdktrace.WithRawSpanLimits(spanLimits),
+	}
+	idGenerator, idGeneratorName := idGeneratorFromEnv()
+	idGeneratorDescription = idGeneratorName
+	if idGenerator != nil {
+		opts = append(opts, sdktrace.WithIDGenerator(idGenerator))
+	}
+	log.Printf("Using trace ID generator: %s", idGeneratorName)
+	// One batch span processor per exporter fans spans out to every destination,
+	// behind attribute redaction and the tail decision that keeps error_biased
+	// traces worth exporting
+	var batchers []sdktrace.SpanProcessor
+	for _, exporter := range exporters {
+		batchers = append(batchers, sdktrace.NewBatchSpanProcessor(exporter,
+			sdktrace.WithMaxQueueSize(bsp.MaxQueueSize),
+			sdktrace.WithMaxExportBatchSize(bsp.MaxExportBatchSize),
+			sdktrace.WithExportTimeout(bsp.ExportTimeout),
+			sdktrace.WithBatchTimeout(bsp.ScheduleDelay),
+		))
+	}
+	redaction, err := newRedactionProcessor(batchers...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create redaction processor: %w", err)
+	}
+	tail, err := newTailSamplingProcessor(redaction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tail sampling processor: %w", err)
+	}
+	opts = append(opts, sdktrace.WithSpanProcessor(tail))
+
+	tp := sdktrace.NewTracerProvider(opts...)
 
 	otel.SetTracerProvider(tp)
-	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
-		propagation.TraceContext{},
-		propagation.Baggage{},
-	))
+	propagator, names := textMapPropagator()
+	otel.SetTextMapPropagator(propagator)
+	propagatorNames = names
+	log.Printf("Using propagators: %v", propagatorNames)
 
 	return tp, nil
 }
 
-func initMeter() (*sdkmetric.MeterProvider, error) {
-	ctx := context.Background()
-
-	// Create OTLP HTTP metrics exporter
-	// Will use OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_METRICS_ENDPOINT env var
-	exporter, err := otlpmetrichttp.New(ctx,
-		otlpmetrichttp.WithInsecure(),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create OTLP metrics exporter: %w", err)
+// exemplarFilter selects the exemplar filter from OTEL_METRICS_EXEMPLAR_FILTER,
+// defaulting to trace_based so data points link to sampled traces
+func exemplarFilter() exemplar.Filter {
+	switch os.Getenv("OTEL_METRICS_EXEMPLAR_FILTER") {
+	case "always_on":
+		return exemplar.AlwaysOnFilter
+	case "always_off":
+		return exemplar.AlwaysOffFilter
+	default:
+		return exemplar.TraceBasedFilter
 	}
+}
 
-	// Create resource
-	res, err := resource.New(ctx,
-		resource.WithAttributes(
-			semconv.ServiceName("go-service"),
-			semconv.ServiceVersion("1.0.0"),
-		),
-	)
+func initMeter(res *resource.Resource) (*sdkmetric.MeterProvider, error) {
+	ctx := context.Background()
+
+	// Create metrics readers (OTLP, console, and/or Prometheus per OTEL_METRICS_EXPORTER)
+	readers, err := newMetricReaders(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create resource: %w", err)
+		return nil, fmt.Errorf("failed to create metrics reader: %w", err)
 	}
 
 	// Create meter provider
-	mp := sdkmetric.NewMeterProvider(
-		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)),
+	opts := []sdkmetric.Option{
 		sdkmetric.WithResource(res),
-	)
+		sdkmetric.WithExemplarFilter(exemplarFilter()),
+		sdkmetric.WithView(metricViews()...),
+	}
+	for _, reader := range readers {
+		opts = append(opts, sdkmetric.WithReader(reader))
+	}
+
+	mp := sdkmetric.NewMeterProvider(opts...)
 
 	otel.SetMeterProvider(mp)
 
 	return mp, nil
 }
 
+func initLogger(res *resource.Resource) (*sdklog.LoggerProvider, error) {
+	ctx := context.Background()
+
+	// Create logs exporter (OTLP or console per OTEL_LOGS_EXPORTER)
+	exporter, err := newLogExporter(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create logs exporter: %w", err)
+	}
+
+	// Create logger provider
+	lp := sdklog.NewLoggerProvider(
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)),
+		sdklog.WithResource(res),
+	)
+
+	global.SetLoggerProvider(lp)
+
+	return lp, nil
+}
+
 func healthHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	_, span := tracer.Start(ctx, "health-check")
@@ -129,7 +232,7 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 
 	response := HealthResponse{
 		Status:    "healthy",
-		Service:   "go-service",
+		Service:   serviceName,
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	}
 
@@ -137,135 +240,513 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-func computeHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	ctx, span := tracer.Start(ctx, "compute-request",
-		trace.WithAttributes(
-			attribute.String("http.method", r.Method),
-			attribute.String("http.url", r.URL.String()),
-		),
-	)
-	defer span.End()
-
-	// Check for error parameter
-	errorParam := r.URL.Query().Get("error")
-	if errorParam == "true" {
-		span.SetAttributes(attribute.Bool("error.requested", true))
-		span.RecordError(fmt.Errorf("requested error triggered"))
+// simulateComputation performs the simulated work shared by the HTTP and gRPC
+// compute APIs for input, annotating the span in ctx. It fails when the call
+// to the simulated dependency fails or ctx is canceled.
+func simulateComputation(ctx context.Context, input ComputeRequest) (ComputeResponse, error) {
+	span := trace.SpanFromContext(ctx)
 
-		errorResponse := ErrorResponse{
-			Error:     "Requested error triggered in Go service",
-			Service:   "go-service",
-			Timestamp: time.Now().UTC().Format(time.RFC3339),
+	// Fetch the inputs from the downstream dependency first
+	if dependency != nil {
+		if err := dependency.Call(ctx, "Quote"); err != nil {
+			return ComputeResponse{}, err
 		}
-
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(errorResponse)
-		return
 	}
 
-	// Simulate computation
-	computeTime := rand.Intn(100) + 20
+	computeTime := 0
+	for range input.iterations() {
+		computeTime += rand.Intn(100) + 20
+	}
+	// slow-path adds a fixed penalty so flag-correlated latency is visible
+	if flagEnabled(ctx, flagSlowPath) {
+		computeTime += 250
+	}
 	span.AddEvent("Starting computation",
 		trace.WithAttributes(attribute.Int("compute.duration_ms", computeTime)),
 	)
 
-	time.Sleep(time.Duration(computeTime) * time.Millisecond)
+	if err := sleepContext(ctx, time.Duration(computeTime)*time.Millisecond); err != nil {
+		recordCancellation(ctx, span, "computation")
+		return ComputeResponse{}, err
+	}
 
-	randomValue := rand.Intn(10000)
-	result := float64(randomValue) * 3.14159
+	randomValue, seeded := input.seed()
+	if !seeded {
+		randomValue = rand.Intn(10000)
+	}
+	algorithm := "v1"
+	if flagEnabled(ctx, flagNewAlgorithm) {
+		algorithm = "v2"
+	}
+	result := 0.0
+	for i := range input.iterations() {
+		if algorithm == "v2" {
+			result += math.Sqrt(float64(randomValue+i)) * 3.14159
+		} else {
+			result += float64(randomValue+i) * 3.14159
+		}
+	}
 
 	span.SetAttributes(
 		attribute.Int("compute.random_value", randomValue),
 		attribute.Float64("compute.result", result),
+		attribute.String("compute.algorithm", algorithm),
 	)
 
 	span.AddEvent("Computation completed")
+	logger.InfoContext(ctx, "Computation completed",
+		"compute.duration_ms", computeTime,
+		"compute.random_value", randomValue,
+		"compute.result", result,
+	)
 
-	response := ComputeResponse{
-		Service:       "go-service",
+	return ComputeResponse{
+		Service:       serviceName,
 		Timestamp:     time.Now().UTC().Format(time.RFC3339),
 		ComputeTimeMs: computeTime,
+		Iterations:    input.iterations(),
 		RandomValue:   randomValue,
 		Result:        result,
+	}, nil
+}
+
+func computeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := tracer.Start(ctx, "compute-request",
+		trace.WithAttributes(httpConventionsFrom(ctx).handlerAttributes(r)...),
+	)
+	defer span.End()
+
+	// POST bodies describe the computation; GET computes a random input
+	var input ComputeRequest
+	if r.Method == http.MethodPost {
+		r.Body = http.MaxBytesReader(w, r.Body, maxComputeBodyBytes)
+		if !decodeBody(ctx, w, r, &input) {
+			return
+		}
+		span.SetAttributes(input.attributes()...)
+	}
+
+	// Check for error parameter, then for a randomly injected error
+	if r.URL.Query().Get("error") == "true" {
+		err := fmt.Errorf("requested error triggered")
+		span.SetAttributes(attribute.Bool("error.requested", true))
+		setErrorType(ctx, span, errorTypeInjected)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		logger.ErrorContext(ctx, "Requested error triggered", "http.method", r.Method)
+
+		writeError(w, http.StatusInternalServerError, "Requested error triggered in Go service")
+		return
+	}
+	if shouldInjectError() {
+		span.SetAttributes(
+			attribute.Bool("error.injected", true),
+			attribute.Float64("error.rate", errorRate()),
+		)
+		setErrorType(ctx, span, errorTypeInjected)
+		err := fmt.Errorf("injected error triggered")
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		logger.ErrorContext(ctx, "Injected error triggered", "http.method", r.Method, "error.rate", errorRate())
+
+		writeError(w, http.StatusInternalServerError, "Injected error triggered in Go service")
+		return
+	}
+
+	// Apply injected latency from ?delay= or LATENCY_MS / LATENCY_P99_MS
+	delayParam := r.URL.Query().Get("delay")
+	delay, err := injectedLatency(delayParam)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if delay > 0 {
+		source := "env"
+		if delayParam != "" {
+			source = "query"
+		}
+		if err := sleepWithSpan(ctx, delay, source); err != nil {
+			recordCancellation(ctx, span, "injected-latency")
+			return
+		}
+		recordServerTiming(ctx, "delay", delay)
+	}
+
+	// Serve from the cache when possible; the key defaults to a random slot
+	// in CACHE_KEYSPACE so load tests see a realistic hit ratio. Results
+	// computed from a request body are never cached.
+	useCache := cache != nil && r.Method != http.MethodPost
+	cacheKey := r.URL.Query().Get("key")
+	if cacheKey == "" {
+		cacheKey = strconv.Itoa(rand.Intn(cacheKeyspace))
+	}
+	if useCache {
+		lookupStart := time.Now()
+		cached, ok := cache.Get(ctx, cacheKey)
+		recordServerTiming(ctx, "cache", time.Since(lookupStart))
+		if ok {
+			span.SetAttributes(attribute.Bool("cache.hit", true))
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(cached)
+			return
+		}
+		span.SetAttributes(attribute.Bool("cache.hit", false))
+	}
+
+	computeStart := time.Now()
+	response, err := simulateComputation(ctx, input)
+	recordServerTiming(ctx, "compute", time.Since(computeStart))
+	if ctx.Err() != nil {
+		// Abandoned mid-computation; don't cache or write a partial result
+		return
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		logger.ErrorContext(ctx, "Dependency call failed", "peer.service", dependency.name, "error", err)
+		writeError(w, http.StatusBadGateway, "Dependency "+dependency.name+" failed")
+		return
+	}
+	if useCache {
+		cache.Set(ctx, cacheKey, response)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-func metricsHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	_, span := tracer.Start(ctx, "metrics")
-	defer span.End()
+// statusRecorder captures the status code and body size written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+	wrote  bool
+	span   trace.Span
+	timing *serverTiming
+}
 
-	metrics := map[string]interface{}{
-		"service":   "go-service",
-		"timestamp": time.Now().UTC().Format(time.RFC3339),
-		"uptime":    time.Now().Unix(),
+// commit adds the Server-Timing header just before the headers go out, so it
+// covers every phase the handler recorded up to that point
+func (rec *statusRecorder) commit() {
+	if rec.wrote {
+		return
+	}
+	rec.wrote = true
+	if rec.timing != nil {
+		rec.Header().Set("Server-Timing", rec.timing.header())
 	}
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(metrics)
+func (rec *statusRecorder) WriteHeader(code int) {
+	rec.status = code
+	rec.commit()
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	rec.commit()
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += int64(n)
+	if isTimeout(err) {
+		recordTimeout(rec.span, "write", err)
+	}
+	return n, err
+}
+
+// Flush lets streaming handlers push partial responses through the recorder
+func (rec *statusRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets the WebSocket handler take over the connection; the response
+// is then a protocol switch rather than a normal status
+func (rec *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rec.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	rec.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// countingBody counts the request body bytes read by a handler
+type countingBody struct {
+	io.ReadCloser
+	bytes int64
+	span  trace.Span
+}
+
+func (c *countingBody) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.bytes += int64(n)
+	if isTimeout(err) {
+		recordTimeout(c.span, "read", err)
+	}
+	return n, err
+}
+
+// httpRoute returns the mux pattern that matched r (e.g. /api/orders/{id}) so
+// spans and metrics carry the route template instead of the raw path
+func httpRoute(r *http.Request) string {
+	if r.Pattern == "" {
+		return r.URL.Path
+	}
+	// Drop any method/host prefix, as in "GET /api/orders/{id}"
+	if i := strings.Index(r.Pattern, "/"); i > 0 {
+		return r.Pattern[i:]
+	}
+	return r.Pattern
 }
 
 // Middleware to extract trace context from incoming requests and increment metrics
 func tracingMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
 		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		// Start a server span so metrics recorded below carry exemplars
+		// pointing at this request's trace
+		route := httpRoute(r)
+		conventions := conventionsFor(r)
+		ctx, span := tracer.Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(conventions.serverRequestAttributes(r, route)...),
+		)
+		defer span.End()
+		ctx = withHTTPConventions(ctx, conventions)
+		ctx, timing := withServerTiming(ctx, span.SpanContext(), start)
+		ctx, errClass := withErrorClass(ctx)
+
+		// Propagate or assign the request ID, echoing it to the caller
+		reqID := requestID(r)
+		ctx = withRequestID(ctx, reqID)
+		span.SetAttributes(attribute.String("request.id", reqID))
+		w.Header().Set(requestIDHeader, reqID)
+
 		r = r.WithContext(ctx)
+		setTraceResponseHeaders(w, span.SpanContext())
 
-		// Increment cows_sold counter on every request
-		cowsSold.Add(ctx, 1, metric.WithAttributes(
-			attribute.String("http.method", r.Method),
-			attribute.String("http.route", r.URL.Path),
-		))
+		// Copy selected baggage entries onto the span and request metrics
+		bagAttrs := baggageAttributes(ctx)
+		span.SetAttributes(bagAttrs...)
+		bagMetricAttrs := baggageMetricAttributes(bagAttrs)
+
+		if unixSocketRequest(ctx) {
+			span.SetAttributes(
+				attribute.String("network.transport", "unix"),
+				attribute.String("network.local.address", unixSocketPath()),
+			)
+		}
+
+		tenant := tenantID(r)
+		if tenant != "" {
+			span.SetAttributes(attribute.String("tenant.id", tenant))
+		}
+
+		// Sell a cow on every request
+		recordCowSale(ctx, r.Method, route)
 
 		// Increment request counter
 		requestCount.Add(ctx, 1, metric.WithAttributes(
 			attribute.String("http.method", r.Method),
-			attribute.String("http.route", r.URL.Path),
-		))
+			attribute.String("http.route", route),
+		), metric.WithAttributes(bagMetricAttrs...))
+
+		// Track in-flight requests for the lifetime of the handler
+		activeAttrs := metric.WithAttributes(attribute.String("http.request.method", r.Method))
+		activeRequests.Add(ctx, 1, activeAttrs)
+		defer activeRequests.Add(ctx, -1, activeAttrs)
+
+		body := &countingBody{ReadCloser: r.Body, span: span}
+		r.Body = body
 
-		next(w, r)
+		// Bound the handler's work by the client's X-Request-Timeout, if any,
+		// and the route's own timeout
+		handlerCtx, cancelDeadline := withClientDeadline(ctx, span, r)
+		defer cancelDeadline()
+		handlerCtx, cancelTimeout := withHandlerTimeout(handlerCtx, route)
+		defer cancelTimeout()
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK, span: span, timing: timing}
+		recoveryMiddleware(rateLimitMiddleware(authMiddleware(compressionMiddleware(decompressionMiddleware(idempotencyMiddleware(next))))))(rec, r.WithContext(handlerCtx))
+
+		// The client's deadline or the route's timeout passed first; say so if
+		// the handler abandoned the request without responding
+		timedOut := deadlineExceeded(ctx, handlerCtx)
+		handlerExpired := handlerTimedOut(ctx, handlerCtx)
+		if timedOut {
+			recordCancellation(handlerCtx, span, "handler")
+			recordDeadlineExceeded(ctx, span, r.Method, route)
+			if !rec.wrote {
+				writeError(rec, http.StatusGatewayTimeout, "Request deadline exceeded")
+			}
+		}
+		if handlerExpired {
+			recordCancellation(handlerCtx, span, "handler")
+			recordHandlerTimeout(ctx, span, r.Method, route)
+			if !rec.wrote {
+				writeError(rec, http.StatusServiceUnavailable, "Handler timed out")
+			}
+		}
+
+		// The request context only ends before ServeHTTP returns when the
+		// client disconnected, so the handler's work was abandoned
+		if ctx.Err() != nil {
+			if !rec.wrote {
+				rec.status = statusClientClosedRequest
+			}
+			recordCancellation(ctx, span, "handler")
+			cancelledRequests.Add(context.WithoutCancel(ctx), 1, metric.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("http.route", route),
+			))
+		}
+		span.SetAttributes(conventions.statusCode(rec.status))
+
+		// A disconnect is the root cause whatever the handler made of it
+		errorType := requestErrorType(errClass.get(), rec.status)
+		if ctx.Err() != nil {
+			errorType = errorTypeCanceled
+		} else if timedOut {
+			errorType = errorTypeDeadlineExceeded
+		} else if handlerExpired {
+			errorType = errorTypeHandlerTimeout
+		}
+		var errorAttrs []attribute.KeyValue
+		if errorType != "" {
+			errorAttrs = append(errorAttrs, attribute.String("error.type", errorType))
+			span.SetAttributes(errorAttrs...)
+			errorCount.Add(ctx, 1, metric.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("http.route", route),
+				attribute.Int("http.response.status_code", rec.status),
+				attribute.String("error.type", errorType),
+			))
+		}
+
+		// Every 4xx and 5xx response marks the server span as an error, so
+		// backend error-rate views count rejected requests too; other
+		// responses leave the status Unset
+		if rec.status >= http.StatusBadRequest {
+			span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d %s", rec.status, http.StatusText(rec.status)))
+		}
+
+		// Prefer the declared Content-Length; fall back to what the handler read
+		reqSize := body.bytes
+		if r.ContentLength > 0 {
+			reqSize = r.ContentLength
+		}
+		span.SetAttributes(conventions.bodySizes(reqSize, rec.bytes)...)
+		sizeAttrs := metric.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("http.route", route),
+			attribute.Int("http.response.status_code", rec.status),
+		)
+		requestBodySize.Record(ctx, reqSize, sizeAttrs)
+		responseBodySize.Record(ctx, rec.bytes, sizeAttrs)
+
+		tenants.Record(ctx, tenant, rec.status)
+
+		// Record request duration per HTTP semantic conventions
+		elapsed := time.Since(start)
+		recordSlowRequest(ctx, span, r.Method, route, elapsed)
+		logAccess(ctx, r, route, rec.status, rec.bytes, elapsed)
+		requestDuration.Record(ctx, elapsed.Seconds(), metric.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("http.route", route),
+			attribute.Int("http.response.status_code", rec.status),
+			attribute.String("network.protocol.version", networkProtocolVersion(r)),
+		), metric.WithAttributes(bagMetricAttrs...), metric.WithAttributes(errorAttrs...))
 	}
 }
 
 func main() {
+	// Apply CONFIG_FILE first so its settings are visible to everything below
+	startup.begin("config")
+	if err := loadConfigFile(); err != nil {
+		startupFatalf("Failed to load config file: %v", err)
+	}
+
+	// Match GOMAXPROCS to the container CPU quota before anything spawns goroutines
+	setMaxProcs()
+
+	// Build the resource describing this service instance
+	startup.begin("resource")
+	loadServiceIdentity()
+	res, err := newResource(context.Background())
+	if err != nil {
+		startupFatalf("Failed to create resource: %v", err)
+	}
+	serviceResource = res
+
+	// Enable SDK self-observability before the providers are built
+	startup.begin("traces")
+	if err := initSDKObservability(); err != nil {
+		startupFatalf("Failed to create SDK observability metrics: %v", err)
+	}
+
 	// Initialize OpenTelemetry tracing
-	tp, err := initTracer()
+	tp, err := initTracer(res)
 	if err != nil {
-		log.Fatalf("Failed to initialize tracer: %v", err)
+		startupFatalf("Failed to initialize tracer: %v", err)
 	}
-	defer func() {
-		if err := tp.Shutdown(context.Background()); err != nil {
-			log.Printf("Error shutting down tracer provider: %v", err)
-		}
-	}()
+	tracerProvider = tp
+	startup.tracerReady(tp)
 
 	// Initialize OpenTelemetry metrics
-	mp, err := initMeter()
+	startup.begin("metrics")
+	mp, err := initMeter(res)
+	if err != nil {
+		startupFatalf("Failed to initialize meter: %v", err)
+	}
+	meterProvider = mp
+
+	// Initialize OpenTelemetry logs
+	startup.begin("logs")
+	lp, err := initLogger(res)
 	if err != nil {
-		log.Fatalf("Failed to initialize meter: %v", err)
+		startupFatalf("Failed to initialize logger: %v", err)
+	}
+	loggerProvider = lp
+
+	// Collect Go runtime metrics (GC, heap, goroutines, GOMAXPROCS)
+	if err := runtime.Start(runtime.WithMeterProvider(mp)); err != nil {
+		startupFatalf("Failed to start runtime metrics: %v", err)
 	}
-	defer func() {
-		if err := mp.Shutdown(context.Background()); err != nil {
-			log.Printf("Error shutting down meter provider: %v", err)
+
+	// Collect host CPU, memory, network, and disk metrics when enabled
+	if getEnvBool("HOST_METRICS_ENABLED", false) {
+		if err := host.Start(host.WithMeterProvider(mp)); err != nil {
+			startupFatalf("Failed to start host metrics: %v", err)
 		}
-	}()
+		log.Printf("Host metrics collection enabled")
+	}
 
 	tracer = otel.Tracer("go-service")
 	meter = otel.Meter("go-service")
+	logger = newLevelLogger(otelslog.NewHandler("go-service"))
 
 	// Create metrics instruments
+	startup.begin("instruments")
 	cowsSold, err = meter.Int64Counter(
 		"cows_sold",
 		metric.WithDescription("The number of cows sold (increments on every request)"),
 		metric.WithUnit("{cows}"),
 	)
 	if err != nil {
-		log.Fatalf("Failed to create cows_sold counter: %v", err)
+		startupFatalf("Failed to create cows_sold counter: %v", err)
+	}
+
+	if err := initBusinessMetrics(); err != nil {
+		startupFatalf("Failed to create business metrics: %v", err)
 	}
 
 	requestCount, err = meter.Int64Counter(
@@ -274,25 +755,329 @@ func main() {
 		metric.WithUnit("{requests}"),
 	)
 	if err != nil {
-		log.Fatalf("Failed to create request counter: %v", err)
+		startupFatalf("Failed to create request counter: %v", err)
+	}
+
+	requestDuration, err = meter.Float64Histogram(
+		"http.server.request.duration",
+		metric.WithDescription("Duration of HTTP server requests"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10),
+	)
+	if err != nil {
+		startupFatalf("Failed to create request duration histogram: %v", err)
+	}
+
+	activeRequests, err = meter.Int64UpDownCounter(
+		"http.server.active_requests",
+		metric.WithDescription("Number of active HTTP server requests"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		startupFatalf("Failed to create active requests counter: %v", err)
+	}
+
+	requestBodySize, err = meter.Int64Histogram(
+		"http.server.request.body.size",
+		metric.WithDescription("Size of HTTP server request bodies"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		startupFatalf("Failed to create request body size histogram: %v", err)
+	}
+
+	responseBodySize, err = meter.Int64Histogram(
+		"http.server.response.body.size",
+		metric.WithDescription("Size of HTTP server response bodies"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		startupFatalf("Failed to create response body size histogram: %v", err)
+	}
+
+	// Open the orders database (instrumented with otelsql)
+	startup.begin("database")
+	db, err = initDB()
+	if err != nil {
+		startupFatalf("Failed to initialize orders database: %v", err)
+	}
+	defer db.Close()
+
+	// Create the compute cache (in-memory, TTL-based)
+	startup.begin("components")
+	cacheKeyspace = max(getEnvInt("CACHE_KEYSPACE", 100), 1)
+	if getEnvBool("CACHE_ENABLED", true) {
+		cache, err = newComputeCache(getEnvDuration("CACHE_TTL", 30*time.Second))
+		if err != nil {
+			startupFatalf("Failed to create compute cache: %v", err)
+		}
+	}
+
+	// Create the simulated downstream dependency the compute path calls
+	if getEnvBool("DEPENDENCY_ENABLED", true) {
+		dependency, err = newDependencySimulator()
+		if err != nil {
+			startupFatalf("Failed to create dependency simulator: %v", err)
+		}
+	}
+
+	if err := initStressMetrics(); err != nil {
+		startupFatalf("Failed to create stress metrics: %v", err)
+	}
+
+	if err := initWebSocketMetrics(); err != nil {
+		startupFatalf("Failed to create WebSocket metrics: %v", err)
+	}
+
+	if err := initCPUMetrics(); err != nil {
+		startupFatalf("Failed to create CPU metrics: %v", err)
+	}
+
+	if err := initCompression(); err != nil {
+		startupFatalf("Failed to create compression metrics: %v", err)
+	}
+
+	if err := initRequestDecompression(); err != nil {
+		startupFatalf("Failed to create request decompression metrics: %v", err)
+	}
+
+	if err := initCircuitBreaker(); err != nil {
+		startupFatalf("Failed to create circuit breaker: %v", err)
+	}
+
+	if err := initRetry(); err != nil {
+		startupFatalf("Failed to create retry metrics: %v", err)
+	}
+
+	if err := initErrorMetrics(); err != nil {
+		startupFatalf("Failed to create error metrics: %v", err)
+	}
+
+	if err := initSlowRequests(); err != nil {
+		startupFatalf("Failed to create slow request metrics: %v", err)
+	}
+
+	initAccessLog()
+
+	if err := initRequestDeadlines(); err != nil {
+		startupFatalf("Failed to create request deadline metrics: %v", err)
+	}
+
+	if err := initHandlerTimeouts(); err != nil {
+		startupFatalf("Failed to create handler timeout metrics: %v", err)
+	}
+
+	if err := initCancellationMetrics(); err != nil {
+		startupFatalf("Failed to create cancellation metrics: %v", err)
+	}
+
+	if err := initCardinalityMetrics(); err != nil {
+		startupFatalf("Failed to create cardinality metrics: %v", err)
+	}
+
+	if err := initTenantMetrics(); err != nil {
+		startupFatalf("Failed to create tenant metrics: %v", err)
+	}
+
+	if err := initFeatureFlags(); err != nil {
+		startupFatalf("Failed to initialize feature flags: %v", err)
+	}
+
+	// Start the background job queue
+	queue, err = newJobQueue(max(getEnvInt("QUEUE_SIZE", 100), 1), max(getEnvInt("QUEUE_WORKERS", 4), 1))
+	if err != nil {
+		startupFatalf("Failed to create job queue: %v", err)
+	}
+	queue.Start()
+
+	// Load error injection settings
+	initFaults()
+
+	if err := initGraphQL(); err != nil {
+		startupFatalf("Failed to create GraphQL schema: %v", err)
+	}
+
+	stopScheduler, err := startScheduler()
+	if err != nil {
+		startupFatalf("Failed to start scheduled jobs: %v", err)
+	}
+
+	stopRateLimiter, err := initRateLimiter()
+	if err != nil {
+		startupFatalf("Failed to create rate limiter: %v", err)
+	}
+
+	if err := initValidationMetrics(); err != nil {
+		startupFatalf("Failed to create validation metrics: %v", err)
+	}
+
+	if err := initIdempotency(); err != nil {
+		startupFatalf("Failed to create idempotency store: %v", err)
+	}
+
+	if err := initAuth(); err != nil {
+		startupFatalf("Failed to create authenticator: %v", err)
+	}
+
+	if err := initConditionalMetrics(); err != nil {
+		startupFatalf("Failed to create conditional request metrics: %v", err)
+	}
+
+	if err := initBuildInfoMetric(); err != nil {
+		startupFatalf("Failed to create build info metric: %v", err)
+	}
+
+	if err := initShutdown(); err != nil {
+		startupFatalf("Failed to create shutdown metrics: %v", err)
+	}
+
+	if err := initConnectionMetrics(); err != nil {
+		startupFatalf("Failed to create connection metrics: %v", err)
 	}
 
 	// Seed random number generator
 	rand.Seed(time.Now().UnixNano())
 
 	// Register handlers with tracing middleware
+	startup.begin("routes")
 	http.HandleFunc("/health", tracingMiddleware(healthHandler))
-	http.HandleFunc("/api/compute", tracingMiddleware(computeHandler))
-	http.HandleFunc("/api/metrics", tracingMiddleware(metricsHandler))
+	http.HandleFunc("GET /page", tracingMiddleware(pageHandler))
+	http.HandleFunc("/api/compute", tracingMiddleware(withETag(computeHandler)))
+	http.HandleFunc("POST /api/compute/batch", tracingMiddleware(batchComputeHandler))
+	http.HandleFunc("/api/chain", tracingMiddleware(chainHandler))
+	http.HandleFunc("/api/v2/compute", tracingMiddleware(withETag(computeHandler)))
+	http.HandleFunc("/api/v2/chain", tracingMiddleware(chainHandler))
+	http.HandleFunc("/api/v2/orders", tracingMiddleware(withETag(ordersHandler)))
+	http.HandleFunc("/api/v2/orders/{id}", tracingMiddleware(withETag(orderHandler)))
+	http.HandleFunc("/api/orders", tracingMiddleware(withETag(ordersHandler)))
+	http.HandleFunc("/graphql", tracingMiddleware(graphqlHandler))
+	http.HandleFunc("/api/orders/{id}", tracingMiddleware(withETag(orderHandler)))
+	http.HandleFunc("/api/fanout", tracingMiddleware(fanoutHandler))
+	http.HandleFunc("/api/links", tracingMiddleware(linksHandler))
+	http.HandleFunc("GET /api/external", tracingMiddleware(externalHandler))
+	http.HandleFunc("/api/stream", tracingMiddleware(streamHandler))
+	http.HandleFunc("/api/payload", tracingMiddleware(payloadHandler))
+	http.HandleFunc("/api/cardinality", tracingMiddleware(cardinalityHandler))
+	http.HandleFunc("/api/limits", tracingMiddleware(limitsHandler))
+	http.HandleFunc("/ws", tracingMiddleware(wsHandler))
+	http.HandleFunc("/api/jobs", tracingMiddleware(jobsHandler))
+	http.HandleFunc("GET /api/jobs/{id}", tracingMiddleware(jobHandler))
+	http.HandleFunc("/api/burn", tracingMiddleware(burnHandler))
+	http.HandleFunc("/api/panic", tracingMiddleware(panicHandler))
+	http.HandleFunc("/api/leak", tracingMiddleware(leakHandler))
+
+	// Serve pprof and the operational endpoints on a separate admin port (set
+	// ADMIN_PORT empty to disable); the operational endpoints stay on the app
+	// port too unless ADMIN_ENDPOINTS_ON_APP_PORT is false
+	adminPort, ok := os.LookupEnv("ADMIN_PORT")
+	if !ok {
+		adminPort = "6060"
+	}
+	if adminPort == "" || getEnvBool("ADMIN_ENDPOINTS_ON_APP_PORT", true) {
+		registerOperationalRoutes(http.DefaultServeMux, adminTokenRequired, unprotected)
+	} else {
+		log.Printf("Operational endpoints are served on the admin port only")
+		if os.Getenv("ADMIN_TOKEN") == "" {
+			log.Printf("Warning: without ADMIN_TOKEN the admin port listens on loopback only, so remote probes and scrapes cannot reach it")
+		}
+	}
 
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	log.Printf("Go service starting on port %s", port)
+	// Serve the compute and health APIs over gRPC on a second port
+	startup.begin("listeners")
+	grpcPort := os.Getenv("GRPC_PORT")
+	if grpcPort == "" {
+		grpcPort = "9090"
+	}
+	grpcServer, err := startGRPCServer(":" + grpcPort)
+	if err != nil {
+		startupFatalf("Failed to start gRPC server: %v", err)
+	}
+	log.Printf("gRPC server listening on port %s", grpcPort)
 
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
+	if adminPort != "" {
+		addr := adminListenAddr(adminPort)
+		adminServer := startAdminServer(addr)
+		defer adminServer.Close()
+		log.Printf("Admin server listening on %s", addr)
+	}
+
+	log.Printf("Go service %s starting on port %s", serviceName, port)
+	logger.Info("Go service starting", "port", port)
+
+	// Bind before serving so a port conflict fails the startup trace
+	server := newHTTPServer(":" + port)
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		startupFatalf("Failed to listen on port %s: %v", port, err)
+	}
+	listeners := []net.Listener{listener}
+	if path := unixSocketPath(); path != "" {
+		unixListener, err := listenUnix(path)
+		if err != nil {
+			startupFatalf("Failed to listen on unix socket %s: %v", path, err)
+		}
+		listeners = append(listeners, unixListener)
+	}
+	startup.finish(nil)
+
+	// Serve HTTPS when a certificate and key are configured
+	serveErr := make(chan error, len(listeners))
+	certFile, keyFile := os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE")
+	if certFile != "" && keyFile != "" {
+		log.Printf("Serving HTTPS with certificate %s", certFile)
+	}
+	for _, listener := range listeners {
+		go func() {
+			if certFile != "" && keyFile != "" {
+				serveErr <- server.ServeTLS(listener, certFile, keyFile)
+			} else {
+				serveErr <- server.Serve(listener)
+			}
+		}()
+	}
+
+	// Run until SIGINT or SIGTERM, then drain in-flight requests and flush
+	// telemetry, each within SHUTDOWN_TIMEOUT; a second signal exits at once
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	reason := ""
+	select {
+	case sig := <-signals:
+		reason = sig.String()
+	case err = <-serveErr:
+		reason = "server_error"
+	}
+	signal.Stop(signals)
+	log.Printf("Shutting down (%s)", reason)
+
+	// HTTP and gRPC drain in parallel; gRPC calls still running at the deadline
+	// are cancelled
+	drainCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+	if shutdownErr := server.Shutdown(drainCtx); shutdownErr != nil {
+		log.Printf("Error draining HTTP requests: %v", shutdownErr)
+	}
+	select {
+	case <-grpcStopped:
+	case <-drainCtx.Done():
+		log.Printf("gRPC calls still running after %s; stopping the gRPC server", shutdownTimeout)
+		grpcServer.Stop()
+		<-grpcStopped
+	}
+	stopScheduler()
+	stopRateLimiter()
+	shutdownTelemetry(reason)
+	if err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+)
+
+// TestMain points the telemetry globals at the no-op providers main would
+// otherwise replace, so handlers and middleware can run without exporters
+func TestMain(m *testing.M) {
+	tracer = otel.Tracer("go-service")
+	meter = otel.Meter("go-service")
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/XSAM/otelsql"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	_ "modernc.org/sqlite"
+)
+
+var db *sql.DB
+
+type Order struct {
+	ID        int64   `json:"id"`
+	Item      string  `json:"item"`
+	Quantity  int     `json:"quantity"`
+	Price     float64 `json:"price"`
+	CreatedAt string  `json:"createdAt"`
+}
+
+// maxOrderItemLength bounds the item name of an order
+const maxOrderItemLength = 200
+
+func (o *Order) validate() []FieldError {
+	var fields []FieldError
+	if strings.TrimSpace(o.Item) == "" {
+		fields = append(fields, FieldError{Field: "item", Reason: reasonRequired, Message: "is required"})
+	} else if len(o.Item) > maxOrderItemLength {
+		fields = append(fields, FieldError{Field: "item", Reason: reasonOutOfRange, Message: fmt.Sprintf("must be at most %d characters", maxOrderItemLength)})
+	}
+	if o.Quantity <= 0 {
+		fields = append(fields, FieldError{Field: "quantity", Reason: reasonOutOfRange, Message: "must be a positive integer"})
+	}
+	if o.Price < 0 {
+		fields = append(fields, FieldError{Field: "price", Reason: reasonOutOfRange, Message: "must not be negative"})
+	}
+	return fields
+}
+
+func initDB() (*sql.DB, error) {
+	// Will use ORDERS_DB_PATH env var, defaulting to orders.db in the working directory
+	path := os.Getenv("ORDERS_DB_PATH")
+	if path == "" {
+		path = "orders.db"
+	}
+
+	// Open SQLite through otelsql so every query produces a child span
+	database, err := otelsql.Open("sqlite", path,
+		otelsql.WithAttributes(semconv.DBSystemSqlite),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{DisableErrSkip: true}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open orders database: %w", err)
+	}
+
+	_, err = database.Exec(`CREATE TABLE IF NOT EXISTS orders (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		item TEXT NOT NULL,
+		quantity INTEGER NOT NULL,
+		price REAL NOT NULL,
+		created_at TEXT NOT NULL
+	)`)
+	if err != nil {
+		database.Close()
+		return nil, fmt.Errorf("failed to create orders table: %w", err)
+	}
+
+	// Pool limits (0 means unlimited) let load tests drive the pool to exhaustion
+	database.SetMaxOpenConns(getEnvInt("DB_MAX_OPEN_CONNS", 0))
+	database.SetMaxIdleConns(getEnvInt("DB_MAX_IDLE_CONNS", 2))
+
+	// Export sql.DBStats (open/in-use/idle connections, wait count and duration)
+	// as db.sql.connection.* observable instruments
+	if err := otelsql.RegisterDBStatsMetrics(database, otelsql.WithAttributes(semconv.DBSystemSqlite)); err != nil {
+		database.Close()
+		return nil, fmt.Errorf("failed to register database pool metrics: %w", err)
+	}
+
+	return database, nil
+}
+
+// recordRowsAffected annotates the current span with the rows affected by a statement
+func recordRowsAffected(ctx context.Context, result sql.Result) int64 {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("db.rows_affected", rows))
+	return rows
+}
+
+// ordersHandler serves GET (list) and POST (create) on /api/orders
+func ordersHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := tracer.Start(ctx, "orders-request")
+	defer span.End()
+
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := db.QueryContext(ctx, "SELECT id, item, quantity, price, created_at FROM orders ORDER BY id")
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			writeError(w, http.StatusInternalServerError, "Failed to list orders")
+			return
+		}
+		defer rows.Close()
+
+		orders := []Order{}
+		for rows.Next() {
+			var order Order
+			if err := rows.Scan(&order.ID, &order.Item, &order.Quantity, &order.Price, &order.CreatedAt); err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				writeError(w, http.StatusInternalServerError, "Failed to read orders")
+				return
+			}
+			orders = append(orders, order)
+		}
+		span.SetAttributes(attribute.Int("orders.count", len(orders)))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(orders)
+
+	case http.MethodPost:
+		var order Order
+		if !decodeBody(ctx, w, r, &order) {
+			return
+		}
+		order.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+
+		result, err := db.ExecContext(ctx,
+			"INSERT INTO orders (item, quantity, price, created_at) VALUES (?, ?, ?, ?)",
+			order.Item, order.Quantity, order.Price, order.CreatedAt,
+		)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			writeError(w, http.StatusInternalServerError, "Failed to create order")
+			return
+		}
+		recordRowsAffected(ctx, result)
+		order.ID, _ = result.LastInsertId()
+		span.SetAttributes(attribute.Int64("order.id", order.ID))
+		logger.InfoContext(ctx, "Order created", "order.id", order.ID, "order.item", order.Item)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(order)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// orderHandler serves GET, PUT, and DELETE on /api/orders/{id}
+func orderHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := tracer.Start(ctx, "order-request")
+	defer span.End()
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid order id")
+		return
+	}
+	span.SetAttributes(attribute.Int64("order.id", id))
+
+	switch r.Method {
+	case http.MethodGet:
+		order := Order{ID: id}
+		err := db.QueryRowContext(ctx,
+			"SELECT item, quantity, price, created_at FROM orders WHERE id = ?", id,
+		).Scan(&order.Item, &order.Quantity, &order.Price, &order.CreatedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "Order not found")
+			return
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			writeError(w, http.StatusInternalServerError, "Failed to read order")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(order)
+
+	case http.MethodPut:
+		var order Order
+		if !decodeBody(ctx, w, r, &order) {
+			return
+		}
+
+		result, err := db.ExecContext(ctx,
+			"UPDATE orders SET item = ?, quantity = ?, price = ? WHERE id = ?",
+			order.Item, order.Quantity, order.Price, id,
+		)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			writeError(w, http.StatusInternalServerError, "Failed to update order")
+			return
+		}
+		if recordRowsAffected(ctx, result) == 0 {
+			writeError(w, http.StatusNotFound, "Order not found")
+			return
+		}
+		order.ID = id
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(order)
+
+	case http.MethodDelete:
+		result, err := db.ExecContext(ctx, "DELETE FROM orders WHERE id = ?", id)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			writeError(w, http.StatusInternalServerError, "Failed to delete order")
+			return
+		}
+		if recordRowsAffected(ctx, result) == 0 {
+			writeError(w, http.StatusNotFound, "Order not found")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// pageTemplate is the demo page. The traceparent meta tag is what browser
+// SDKs (e.g. the OpenTelemetry JS document-load instrumentation) read to
+// parent the page-load span under this server span.
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Service}}</title>
+{{- if .Traceparent}}
+<meta name="traceparent" content="{{.Traceparent}}">
+{{- end}}
+{{.Snippet}}
+</head>
+<body>
+<h1>{{.Service}}</h1>
+<p>Server trace ID: <code>{{.TraceID}}</code></p>
+<button id="compute">Call /api/compute</button>
+<pre id="result"></pre>
+<script>
+document.getElementById("compute").addEventListener("click", async () => {
+  const response = await fetch("/api/compute");
+  const body = await response.text();
+  document.getElementById("result").textContent =
+    "traceresponse: " + response.headers.get("traceresponse") + "\n" + body;
+});
+</script>
+</body>
+</html>
+`))
+
+type pageData struct {
+	Service     string
+	Traceparent string
+	TraceID     string
+	Snippet     template.HTML
+}
+
+// browserSnippet returns the RUM/browser-SDK snippet injected into the page
+// head, from RUM_SNIPPET_FILE or RUM_SNIPPET. It is operator-supplied
+// configuration, so it is trusted and not escaped.
+func browserSnippet() template.HTML {
+	if path := os.Getenv("RUM_SNIPPET_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("Failed to read RUM_SNIPPET_FILE %q: %v", path, err)
+			return ""
+		}
+		return template.HTML(data)
+	}
+	return template.HTML(os.Getenv("RUM_SNIPPET"))
+}
+
+// pageHandler serves a small HTML page carrying the server span's traceparent
+// so front-end telemetry can be stitched to the back-end trace
+func pageHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	_, span := tracer.Start(ctx, "page-request")
+	defer span.End()
+
+	// The page load belongs to the server span, which is what the browser's
+	// traceresponse header also reports
+	sc := trace.SpanContextFromContext(ctx)
+	data := pageData{Service: serviceName, Snippet: browserSnippet()}
+	if sc.IsValid() {
+		data.Traceparent = traceparent(sc)
+		data.TraceID = sc.TraceID().String()
+	}
+	span.SetAttributes(attribute.Bool("page.rum_snippet", data.Snippet != ""))
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := pageTemplate.Execute(w, data); err != nil {
+		span.RecordError(err)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// maxPayloadKB caps the size of generated /api/payload documents
+	maxPayloadKB = 10 * 1024
+	// payloadItemBytes is the approximate encoded size of one payload item
+	payloadItemBytes = 1024
+)
+
+const payloadAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+type PayloadItem struct {
+	Index int    `json:"index"`
+	Data  string `json:"data"`
+}
+
+type PayloadResponse struct {
+	Service   string        `json:"service"`
+	Timestamp string        `json:"timestamp"`
+	SizeKB    int           `json:"sizeKb"`
+	Items     []PayloadItem `json:"items"`
+}
+
+// randomString returns n random alphanumeric characters
+func randomString(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = payloadAlphabet[rand.Intn(len(payloadAlphabet))]
+	}
+	return string(b)
+}
+
+// payloadHandler returns a generated JSON document of roughly ?kb= kilobytes
+// (default 64); compressionMiddleware gzips it when the client accepts it
+func payloadHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	_, span := tracer.Start(ctx, "payload-request")
+	defer span.End()
+
+	kb := 64
+	if value := r.URL.Query().Get("kb"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > maxPayloadKB {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("kb must be an integer between 1 and %d", maxPayloadKB))
+			return
+		}
+		kb = parsed
+	}
+
+	// Each item's data is sized so the encoded item is about payloadItemBytes
+	const itemOverhead = len(`{"index":00000,"data":""},`)
+	items := make([]PayloadItem, kb*1024/payloadItemBytes)
+	for i := range items {
+		items[i] = PayloadItem{Index: i, Data: randomString(payloadItemBytes - itemOverhead)}
+	}
+
+	start := time.Now()
+	body, err := json.Marshal(PayloadResponse{
+		Service:   serviceName,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		SizeKB:    kb,
+		Items:     items,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to encode payload")
+		return
+	}
+	span.SetAttributes(
+		attribute.Int("payload.requested_kb", kb),
+		attribute.Int("payload.items", len(items)),
+		attribute.Int("payload.bytes", len(body)),
+		attribute.Float64("payload.serialize_ms", float64(time.Since(start).Microseconds())/1000),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.Write(body)
+}
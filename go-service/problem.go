@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Problem types beyond the status-only about:blank
+const problemTypeValidation = "urn:go-service:problem:validation"
+
+// ProblemDetails is an RFC 7807 application/problem+json error body. Service,
+// timestamp, and traceId are extension members; traceId names the trace of
+// the failed request so clients can look it up.
+type ProblemDetails struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Service   string `json:"service"`
+	Timestamp string `json:"timestamp"`
+	TraceID   string `json:"traceId,omitempty"`
+}
+
+// newProblem builds an about:blank problem for status. The trace ID is the
+// one the tracing middleware already returned in X-Trace-Id, so untraced
+// routes such as the admin port simply omit it.
+func newProblem(w http.ResponseWriter, status int, detail string) ProblemDetails {
+	return ProblemDetails{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Service:   serviceName,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		TraceID:   w.Header().Get("X-Trace-Id"),
+	}
+}
+
+// writeProblem sends body, a ProblemDetails or a type embedding one, as
+// problem+json
+func writeProblem(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError responds with an about:blank problem whose detail is message
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeProblem(w, status, newProblem(w, status, message))
+}
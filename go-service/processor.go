@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// globalAttributesProcessor stamps every span with attributes read once at
+// startup, giving consistent dimensions across all spans
+type globalAttributesProcessor struct {
+	attrs []attribute.KeyValue
+}
+
+var _ sdktrace.SpanProcessor = globalAttributesProcessor{}
+
+// newGlobalAttributesProcessor reads REGION, BUILD_SHA, and FEATURE_FLAGS
+// (comma-separated names of enabled flags) from the environment
+func newGlobalAttributesProcessor() globalAttributesProcessor {
+	var attrs []attribute.KeyValue
+	if region := os.Getenv("REGION"); region != "" {
+		attrs = append(attrs, attribute.String("cloud.region", region))
+	}
+	if sha := os.Getenv("BUILD_SHA"); sha != "" {
+		attrs = append(attrs, attribute.String("build.sha", sha))
+	}
+	if flags := os.Getenv("FEATURE_FLAGS"); flags != "" {
+		attrs = append(attrs, attribute.StringSlice("feature_flags.enabled", splitList(flags)))
+	}
+	return globalAttributesProcessor{attrs: attrs}
+}
+
+func (p globalAttributesProcessor) OnStart(_ context.Context, s sdktrace.ReadWriteSpan) {
+	s.SetAttributes(p.attrs...)
+}
+
+func (globalAttributesProcessor) OnEnd(sdktrace.ReadOnlySpan) {}
+
+func (globalAttributesProcessor) Shutdown(context.Context) error { return nil }
+
+func (globalAttributesProcessor) ForceFlush(context.Context) error { return nil }
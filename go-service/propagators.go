@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/contrib/propagators/jaeger"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceparent formats sc as a W3C traceparent value
+func traceparent(sc trace.SpanContext) string {
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID(), sc.SpanID(), sc.TraceFlags())
+}
+
+// setTraceResponseHeaders returns the server span's identity to the client as
+// a W3C Trace Context Level 2 traceresponse header plus a plain X-Trace-Id, so
+// callers can correlate a response (or failure) with its trace
+func setTraceResponseHeaders(w http.ResponseWriter, sc trace.SpanContext) {
+	if !sc.IsValid() {
+		return
+	}
+	w.Header().Set("traceresponse", traceparent(sc))
+	w.Header().Set("X-Trace-Id", sc.TraceID().String())
+}
+
+// textMapPropagator builds the composite propagator from OTEL_PROPAGATORS
+// (comma-separated: tracecontext, baggage, b3, b3multi, jaeger, none),
+// defaulting to tracecontext,baggage. It returns the names actually used.
+func textMapPropagator() (propagation.TextMapPropagator, []string) {
+	names := splitList(os.Getenv("OTEL_PROPAGATORS"))
+	if len(names) == 0 {
+		names = []string{"tracecontext", "baggage"}
+	}
+
+	var propagators []propagation.TextMapPropagator
+	var used []string
+	for _, name := range names {
+		switch name {
+		case "tracecontext":
+			propagators = append(propagators, propagation.TraceContext{})
+		case "baggage":
+			propagators = append(propagators, propagation.Baggage{})
+		case "b3":
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3SingleHeader)))
+		case "b3multi":
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		case "jaeger":
+			propagators = append(propagators, jaeger.Jaeger{})
+		case "none":
+			return propagation.NewCompositeTextMapPropagator(), []string{"none"}
+		default:
+			log.Printf("Unsupported propagator %q in OTEL_PROPAGATORS, skipping", name)
+			continue
+		}
+		used = append(used, name)
+	}
+	return propagation.NewCompositeTextMapPropagator(propagators...), used
+}
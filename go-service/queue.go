@@ -0,0 +1,412 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	queueSystem      = "go-service"
+	queueDestination = "jobs"
+)
+
+// maxTrackedJobs bounds how many job statuses are kept for GET /api/jobs/{id}
+const maxTrackedJobs = 1000
+
+// maxJobDuration caps the simulated work time a client can request
+const maxJobDuration = 60 * time.Second
+
+var errQueueFull = errors.New("job queue is full")
+
+// Job lifecycle states reported by GET /api/jobs/{id}
+const (
+	jobQueued    = "queued"
+	jobRunning   = "running"
+	jobSucceeded = "succeeded"
+)
+
+// job is a unit of work carried through the queue along with the trace
+// context of the request that enqueued it
+type job struct {
+	ID         string
+	Payload    string
+	Duration   time.Duration
+	EnqueuedAt time.Time
+	Carrier    propagation.MapCarrier
+}
+
+// JobStatus is the externally visible state of a job. TraceID is the trace of
+// the background execution, which links back to SubmitTraceID.
+type JobStatus struct {
+	ID            string `json:"jobId"`
+	Status        string `json:"status"`
+	SubmittedAt   string `json:"submittedAt"`
+	StartedAt     string `json:"startedAt,omitempty"`
+	FinishedAt    string `json:"finishedAt,omitempty"`
+	SubmitTraceID string `json:"submitTraceId,omitempty"`
+	TraceID       string `json:"traceId,omitempty"`
+}
+
+// jobQueue is an in-process queue drained by a pool of workers
+type jobQueue struct {
+	jobs    chan job
+	workers int
+
+	mu       sync.Mutex
+	statuses map[string]*JobStatus
+	order    []string
+	// queued holds the enqueue time of every job still waiting for a worker
+	queued map[string]time.Time
+
+	queueLatency    metric.Float64Histogram
+	processDuration metric.Float64Histogram
+	sent            metric.Int64Counter
+	consumed        metric.Int64Counter
+}
+
+func newJobQueue(size, workers int) (*jobQueue, error) {
+	q := &jobQueue{
+		jobs:     make(chan job, size),
+		workers:  workers,
+		statuses: make(map[string]*JobStatus),
+		queued:   make(map[string]time.Time),
+	}
+
+	var err error
+	q.queueLatency, err = meter.Float64Histogram(
+		"messaging.queue.latency",
+		metric.WithDescription("Time jobs spend waiting in the queue before processing starts"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	q.processDuration, err = meter.Float64Histogram(
+		"messaging.process.duration",
+		metric.WithDescription("Time workers spend processing a job"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	q.sent, err = meter.Int64Counter(
+		"messaging.client.sent.messages",
+		metric.WithDescription("Number of jobs enqueued"),
+		metric.WithUnit("{message}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	q.consumed, err = meter.Int64Counter(
+		"messaging.client.consumed.messages",
+		metric.WithDescription("Number of jobs processed by workers"),
+		metric.WithUnit("{message}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	depth, err := meter.Int64ObservableGauge(
+		"messaging.queue.depth",
+		metric.WithDescription("Number of jobs waiting in the queue"),
+		metric.WithUnit("{message}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	oldestAge, err := meter.Float64ObservableGauge(
+		"messaging.queue.oldest_age",
+		metric.WithDescription("How long the oldest waiting job has been in the queue (0 when empty)"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		attrs := metric.WithAttributes(
+			attribute.String("messaging.system", queueSystem),
+			attribute.String("messaging.destination.name", queueDestination),
+		)
+		o.ObserveInt64(depth, int64(len(q.jobs)), attrs)
+		o.ObserveFloat64(oldestAge, q.oldestAge().Seconds(), attrs)
+		return nil
+	}, depth, oldestAge)
+	if err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+func messagingAttributes(operation string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("messaging.system", queueSystem),
+		attribute.String("messaging.destination.name", queueDestination),
+		attribute.String("messaging.operation.type", operation),
+	}
+}
+
+// Start launches the worker pool
+func (q *jobQueue) Start() {
+	for i := 0; i < q.workers; i++ {
+		go q.work()
+	}
+}
+
+// track records a new job's status, evicting the oldest beyond maxTrackedJobs
+func (q *jobQueue) track(status *JobStatus, enqueuedAt time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.queued[status.ID] = enqueuedAt
+	q.statuses[status.ID] = status
+	q.order = append(q.order, status.ID)
+	if len(q.order) > maxTrackedJobs {
+		delete(q.statuses, q.order[0])
+		q.order = q.order[1:]
+	}
+}
+
+// untrack forgets a job that was never published. Its ID is usually the
+// newest in order, so the search starts from the end.
+func (q *jobQueue) untrack(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.statuses, id)
+	delete(q.queued, id)
+	for i := len(q.order) - 1; i >= 0; i-- {
+		if q.order[i] == id {
+			q.order = slices.Delete(q.order, i, i+1)
+			break
+		}
+	}
+}
+
+// dequeued stops counting a job towards the queue's oldest age
+func (q *jobQueue) dequeued(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.queued, id)
+}
+
+// oldestAge returns how long the longest-waiting job has been queued
+func (q *jobQueue) oldestAge() time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var oldest time.Duration
+	now := time.Now()
+	for _, enqueuedAt := range q.queued {
+		oldest = max(oldest, now.Sub(enqueuedAt))
+	}
+	return oldest
+}
+
+// update applies fn to a tracked job's status under the lock
+func (q *jobQueue) update(id string, fn func(*JobStatus)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if status, ok := q.statuses[id]; ok {
+		fn(status)
+	}
+}
+
+// Status returns a copy of a tracked job's status
+func (q *jobQueue) Status(id string) (JobStatus, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	status, ok := q.statuses[id]
+	if !ok {
+		return JobStatus{}, false
+	}
+	return *status, true
+}
+
+// Enqueue publishes a job inside a PRODUCER span, injecting its trace context
+// into the job so the consumer can link back to it. A zero duration picks a
+// short random amount of simulated work.
+func (q *jobQueue) Enqueue(ctx context.Context, payload string, duration time.Duration) (string, error) {
+	id := fmt.Sprintf("%016x", rand.Uint64())
+
+	ctx, span := tracer.Start(ctx, "send "+queueDestination,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(messagingAttributes("send")...),
+		trace.WithAttributes(attribute.String("messaging.message.id", id)),
+	)
+	defer span.End()
+
+	if duration <= 0 {
+		duration = time.Duration(rand.Intn(150)+50) * time.Millisecond
+	}
+	j := job{
+		ID:         id,
+		Payload:    payload,
+		Duration:   duration,
+		EnqueuedAt: time.Now(),
+		Carrier:    propagation.MapCarrier{},
+	}
+	otel.GetTextMapPropagator().Inject(ctx, j.Carrier)
+
+	// Track before publishing so a fast worker always finds the entry
+	q.track(&JobStatus{
+		ID:            id,
+		Status:        jobQueued,
+		SubmittedAt:   j.EnqueuedAt.UTC().Format(time.RFC3339Nano),
+		SubmitTraceID: span.SpanContext().TraceID().String(),
+	}, j.EnqueuedAt)
+
+	select {
+	case q.jobs <- j:
+	default:
+		q.untrack(id)
+		span.RecordError(errQueueFull)
+		span.SetStatus(codes.Error, errQueueFull.Error())
+		return "", errQueueFull
+	}
+
+	q.sent.Add(ctx, 1, metric.WithAttributes(messagingAttributes("send")...))
+	return id, nil
+}
+
+func (q *jobQueue) work() {
+	for j := range q.jobs {
+		q.process(j)
+	}
+}
+
+// process handles one job inside a CONSUMER span that starts its own trace,
+// linked to the producer span of the submitting request
+func (q *jobQueue) process(j job) {
+	producerCtx := otel.GetTextMapPropagator().Extract(context.Background(), j.Carrier)
+	ctx, span := tracer.Start(context.Background(), "process "+queueDestination,
+		trace.WithNewRoot(),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithLinks(trace.LinkFromContext(producerCtx, attribute.String("link.type", "submitted_by"))),
+		trace.WithAttributes(messagingAttributes("process")...),
+		trace.WithAttributes(attribute.String("messaging.message.id", j.ID)),
+	)
+	defer span.End()
+
+	q.dequeued(j.ID)
+	q.update(j.ID, func(s *JobStatus) {
+		s.Status = jobRunning
+		s.StartedAt = time.Now().UTC().Format(time.RFC3339Nano)
+		s.TraceID = span.SpanContext().TraceID().String()
+	})
+
+	latency := time.Since(j.EnqueuedAt)
+	span.SetAttributes(attribute.Int64("messaging.queue.latency_ms", latency.Milliseconds()))
+	q.queueLatency.Record(ctx, latency.Seconds(), metric.WithAttributes(messagingAttributes("process")...))
+
+	// Simulate work
+	start := time.Now()
+	span.SetAttributes(attribute.Int64("job.duration_ms", j.Duration.Milliseconds()))
+	time.Sleep(j.Duration)
+	q.processDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(messagingAttributes("process")...))
+
+	q.update(j.ID, func(s *JobStatus) {
+		s.Status = jobSucceeded
+		s.FinishedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	})
+	q.consumed.Add(ctx, 1, metric.WithAttributes(messagingAttributes("process")...))
+	logger.InfoContext(ctx, "Job processed", "messaging.message.id", j.ID, "job.payload", j.Payload)
+}
+
+type JobRequest struct {
+	Payload    string `json:"payload"`
+	DurationMs int    `json:"durationMs"`
+}
+
+func (j *JobRequest) validate() []FieldError {
+	if j.DurationMs < 0 || j.DurationMs > int(maxJobDuration.Milliseconds()) {
+		return []FieldError{{Field: "durationMs", Reason: reasonOutOfRange, Message: fmt.Sprintf("must be between 0 and %d", maxJobDuration.Milliseconds())}}
+	}
+	return nil
+}
+
+type JobResponse struct {
+	Service   string `json:"service"`
+	Timestamp string `json:"timestamp"`
+	JobID     string `json:"jobId"`
+	Status    string `json:"status"`
+	StatusURL string `json:"statusUrl"`
+}
+
+// jobsHandler enqueues a job (POST, optional {"payload": "...", "durationMs": 5000})
+// and returns 202 with the job ID to poll
+func jobsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := tracer.Start(ctx, "jobs-request")
+	defer span.End()
+
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var request JobRequest
+	if r.ContentLength != 0 && !decodeBody(ctx, w, r, &request) {
+		return
+	}
+	duration := time.Duration(request.DurationMs) * time.Millisecond
+
+	id, err := queue.Enqueue(ctx, request.Payload, duration)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	span.SetAttributes(attribute.String("messaging.message.id", id))
+
+	response := JobResponse{
+		Service:   serviceName,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		JobID:     id,
+		Status:    jobQueued,
+		StatusURL: "/api/jobs/" + id,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", response.StatusURL)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(response)
+}
+
+// jobHandler reports the status of a job submitted to /api/jobs
+func jobHandler(w http.ResponseWriter, r *http.Request) {
+	_, span := tracer.Start(r.Context(), "job-status")
+	defer span.End()
+
+	id := r.PathValue("id")
+	span.SetAttributes(attribute.String("messaging.message.id", id))
+
+	status, ok := queue.Status(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+	span.SetAttributes(attribute.String("job.status", status.Status))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEnqueueRejectedJobIsUntracked(t *testing.T) {
+	// No workers are started, so the second job finds the queue full
+	q, err := newJobQueue(1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	id, err := q.Enqueue(ctx, "first", time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		if _, err := q.Enqueue(ctx, "rejected", time.Millisecond); !errors.Is(err, errQueueFull) {
+			t.Fatalf("Enqueue on a full queue = %v, want errQueueFull", err)
+		}
+	}
+
+	if len(q.order) != 1 || q.order[0] != id {
+		t.Errorf("order = %v, want only the accepted job %s", q.order, id)
+	}
+	if len(q.statuses) != 1 || len(q.queued) != 1 {
+		t.Errorf("tracking %d statuses and %d queued jobs, want 1 each", len(q.statuses), len(q.queued))
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// bucketIdleTimeout is how long an idle client's bucket is kept before it is
+// swept, and bucketSweepInterval how often the sweep runs
+const (
+	bucketIdleTimeout   = 5 * time.Minute
+	bucketSweepInterval = time.Minute
+)
+
+// trustedProxies are the peers (TRUSTED_PROXIES) whose X-Forwarded-For is
+// believed; anyone else could name any address there
+var trustedProxies []netip.Prefix
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter is a token-bucket limiter keyed by client IP
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	rate    float64
+	burst   float64
+
+	rejected metric.Int64Counter
+}
+
+var limiter *rateLimiter
+
+func newRateLimiter(rps float64, burst int) (*rateLimiter, error) {
+	l := &rateLimiter{
+		buckets: make(map[string]*tokenBucket),
+		rate:    rps,
+		burst:   float64(burst),
+	}
+
+	var err error
+	l.rejected, err = meter.Int64Counter(
+		"http.server.rate_limited.requests",
+		metric.WithDescription("The number of requests rejected by the per-client rate limiter"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// initRateLimiter reads TRUSTED_PROXIES, then enables per-client rate
+// limiting when RATE_LIMIT_RPS is positive; RATE_LIMIT_BURST defaults to one
+// second's worth of requests. Idle buckets are swept in the background until
+// the returned stop function is called.
+func initRateLimiter() (stop func(), err error) {
+	trustedProxies, err = parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		return nil, err
+	}
+
+	rps, err := strconv.ParseFloat(os.Getenv("RATE_LIMIT_RPS"), 64)
+	if err != nil || rps <= 0 {
+		return func() {}, nil
+	}
+	burst := max(getEnvInt("RATE_LIMIT_BURST", int(math.Ceil(rps))), 1)
+
+	limiter, err = newRateLimiter(rps, burst)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(bucketSweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				limiter.sweep(now)
+			}
+		}
+	}()
+	log.Printf("Rate limiting enabled: %g requests/s per client, burst %d", rps, burst)
+	return func() {
+		cancel()
+		<-done
+	}, nil
+}
+
+// parseTrustedProxies parses a comma-separated list of IPs and CIDR ranges
+func parseTrustedProxies(value string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range splitList(value) {
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry %q: %w", entry, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry %q: %w", entry, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// trustedProxy reports whether ip is one of trustedProxies
+func trustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allow takes a token from the client's bucket. When the bucket is empty it
+// returns false along with how long until the next token is available.
+func (l *rateLimiter) Allow(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// sweep drops buckets that have been idle long enough to have refilled
+func (l *rateLimiter) sweep(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for client, b := range l.buckets {
+		if now.Sub(b.last) > bucketIdleTimeout {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// clientIP returns the request's remote address or, when that is a trusted
+// proxy, the nearest X-Forwarded-For hop that is not itself a trusted proxy.
+// X-Forwarded-For from any other peer is ignored, since the client wrote it.
+func clientIP(r *http.Request) string {
+	client, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		client = r.RemoteAddr
+	}
+	if !trustedProxy(client) {
+		return client
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		client = hop
+		if !trustedProxy(hop) {
+			break
+		}
+	}
+	return client
+}
+
+// rateLimitMiddleware rejects requests over RATE_LIMIT_RPS per client IP with
+// 429 and Retry-After, annotating the request span. Health probes are exempt.
+func rateLimitMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health", "/healthz", "/readyz":
+			next(w, r)
+			return
+		}
+		if limiter == nil {
+			next(w, r)
+			return
+		}
+
+		ctx := r.Context()
+		span := trace.SpanFromContext(ctx)
+		allowed, wait := limiter.Allow(clientIP(r))
+		span.SetAttributes(attribute.Bool("rate_limit.limited", !allowed))
+		if allowed {
+			next(w, r)
+			return
+		}
+
+		retryAfter := int(math.Ceil(wait.Seconds()))
+		span.AddEvent("rate_limited", trace.WithAttributes(
+			attribute.String("client.address", clientIP(r)),
+			attribute.Int("rate_limit.retry_after_s", retryAfter),
+		))
+		limiter.rejected.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("http.route", httpRoute(r)),
+		))
+
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		writeError(w, http.StatusTooManyRequests, "Rate limit exceeded")
+	}
+}
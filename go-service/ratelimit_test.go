@@ -0,0 +1,155 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		trusted    string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{"no proxies trusted", "", "203.0.113.7:5000", nil, "203.0.113.7"},
+		{"forwarded from untrusted peer is ignored", "", "203.0.113.7:5000", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"forwarded from other untrusted peer is ignored", "10.0.0.1", "203.0.113.7:5000", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"trusted proxy", "10.0.0.1", "10.0.0.1:5000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"trusted CIDR", "10.0.0.0/8", "10.1.2.3:5000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"spoofed first hop", "10.0.0.1", "10.0.0.1:5000", []string{"1.2.3.4, 198.51.100.1"}, "198.51.100.1"},
+		{"chain of trusted proxies", "10.0.0.0/8", "10.0.0.1:5000", []string{"198.51.100.1, 10.0.0.2, 10.0.0.3"}, "198.51.100.1"},
+		{"repeated headers", "10.0.0.1", "10.0.0.1:5000", []string{"1.2.3.4", "198.51.100.1"}, "198.51.100.1"},
+		{"all hops trusted", "10.0.0.0/8", "10.0.0.1:5000", []string{"10.0.0.2"}, "10.0.0.2"},
+		{"trusted proxy without header", "10.0.0.1", "10.0.0.1:5000", nil, "10.0.0.1"},
+		{"empty hops skipped", "10.0.0.1", "10.0.0.1:5000", []string{"198.51.100.1, "}, "198.51.100.1"},
+		{"IPv6 proxy", "2001:db8::/32", "[2001:db8::1]:5000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"remote address without port", "", "203.0.113.7", nil, "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxies, err := parseTrustedProxies(tt.trusted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			saved := trustedProxies
+			trustedProxies = proxies
+			t.Cleanup(func() { trustedProxies = saved })
+
+			req := httptest.NewRequest(http.MethodGet, "/api/compute", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if got := clientIP(req); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"10.0.0.1", 1, false},
+		{"10.0.0.0/8, 192.168.1.1, ::1", 3, false},
+		{"proxy.internal", 0, true},
+		{"10.0.0.0/33", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseTrustedProxies(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTrustedProxies(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if len(got) != tt.want {
+				t.Errorf("parseTrustedProxies(%q) = %v, want %d prefixes", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimiterAllow(t *testing.T) {
+	tests := []struct {
+		name     string
+		burst    int
+		requests int
+		allowed  int
+	}{
+		{"within burst", 3, 3, 3},
+		{"beyond burst", 3, 5, 3},
+		{"burst of one", 1, 4, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A rate slow enough that no token refills during the test
+			l, err := newRateLimiter(0.001, tt.burst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			allowed := 0
+			for range tt.requests {
+				ok, retryAfter := l.Allow("203.0.113.7")
+				if ok {
+					allowed++
+				} else if retryAfter <= 0 {
+					t.Errorf("rejected request has retry-after %s, want positive", retryAfter)
+				}
+			}
+			if allowed != tt.allowed {
+				t.Errorf("allowed %d of %d requests, want %d", allowed, tt.requests, tt.allowed)
+			}
+			if ok, _ := l.Allow("198.51.100.1"); !ok {
+				t.Error("another client was rejected; buckets should be per client")
+			}
+		})
+	}
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	l, err := newRateLimiter(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Allow("idle")
+	l.Allow("active")
+	now := time.Now()
+	l.buckets["idle"].last = now.Add(-bucketIdleTimeout - time.Second)
+
+	l.sweep(now)
+	if _, ok := l.buckets["idle"]; ok {
+		t.Error("idle bucket was not swept")
+	}
+	if _, ok := l.buckets["active"]; !ok {
+		t.Error("active bucket was swept")
+	}
+}
+
+func TestInitRateLimiterStop(t *testing.T) {
+	t.Setenv("RATE_LIMIT_RPS", "5")
+	t.Setenv("TRUSTED_PROXIES", "")
+	saved := limiter
+	t.Cleanup(func() { limiter = saved })
+
+	stop, err := initRateLimiter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stopped := make(chan struct{})
+	go func() {
+		stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("stop did not end the bucket sweeper")
+	}
+}
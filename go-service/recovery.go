@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recoveryMiddleware converts handler panics into 500 responses, recording
+// the panic as an exception event (with stack trace) on the request span
+func recoveryMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			ctx := r.Context()
+			err := fmt.Errorf("panic: %v", recovered)
+
+			span := trace.SpanFromContext(ctx)
+			span.RecordError(err,
+				trace.WithStackTrace(true),
+				trace.WithAttributes(attribute.Bool("exception.escaped", true)),
+			)
+			span.SetStatus(codes.Error, err.Error())
+			setErrorType(ctx, span, errorTypePanic)
+			logger.ErrorContext(ctx, "Recovered from panic", "error", err, "url.path", r.URL.Path)
+
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+		}()
+
+		next(w, r)
+	}
+}
+
+// panicHandler deliberately panics to exercise recoveryMiddleware
+func panicHandler(w http.ResponseWriter, r *http.Request) {
+	_, span := tracer.Start(r.Context(), "panic-request")
+	defer span.End()
+
+	panic("deliberate panic triggered via /api/panic")
+}
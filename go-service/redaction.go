@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Default redaction lists; setting the variable (even to "") replaces them
+const (
+	defaultRedactKeys        = "http.request.header.authorization,http.request.header.cookie,http.request.header.x-api-key,http.response.header.set-cookie"
+	defaultHashKeys          = "enduser.email,user.email"
+	defaultRedactQueryParams = "token,access_token,id_token,api_key,apikey,password,secret,code"
+)
+
+// urlAttributeKeys carry a URL (false) or bare query string (true) whose
+// sensitive parameters are redacted in place
+var urlAttributeKeys = map[attribute.Key]bool{
+	"url.query":   true,
+	"url.full":    false,
+	"http.target": false,
+	"http.url":    false,
+}
+
+// Redaction actions recorded on span.attributes.redacted
+const (
+	redactActionRedact = "redact"
+	redactActionHash   = "hash"
+	redactActionQuery  = "query_param"
+)
+
+// attributeRedactor rewrites attribute values before export. Keys are matched
+// exactly or, for patterns ending in "*", by prefix; the allow list wins over
+// the redact and hash lists.
+type attributeRedactor struct {
+	allow       []string
+	redact      []string
+	hash        []string
+	queryParams map[string]bool
+	salt        string
+	redactions  metric.Int64Counter
+}
+
+// envList reads a comma-separated list, using fallback only when key is unset
+func envList(key, fallback string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		value = fallback
+	}
+	return splitList(strings.ToLower(value))
+}
+
+// newAttributeRedactor reads ATTRIBUTE_REDACT_KEYS, ATTRIBUTE_HASH_KEYS,
+// ATTRIBUTE_ALLOW_KEYS, ATTRIBUTE_REDACT_QUERY_PARAMS, and ATTRIBUTE_HASH_SALT
+func newAttributeRedactor() (*attributeRedactor, error) {
+	r := &attributeRedactor{
+		allow:       envList("ATTRIBUTE_ALLOW_KEYS", ""),
+		redact:      envList("ATTRIBUTE_REDACT_KEYS", defaultRedactKeys),
+		hash:        envList("ATTRIBUTE_HASH_KEYS", defaultHashKeys),
+		queryParams: make(map[string]bool),
+		salt:        os.Getenv("ATTRIBUTE_HASH_SALT"),
+	}
+	for _, name := range envList("ATTRIBUTE_REDACT_QUERY_PARAMS", defaultRedactQueryParams) {
+		r.queryParams[name] = true
+	}
+
+	// The meter provider does not exist yet; the global meter delegates to it
+	var err error
+	r.redactions, err = otel.Meter("go-service").Int64Counter(
+		"span.attributes.redacted",
+		metric.WithDescription("Span and span event attribute values redacted or hashed before export, by action"),
+		metric.WithUnit("{attribute}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Attribute redaction: redact=%v hash=%v allow=%v query_params=%d",
+		r.redact, r.hash, r.allow, len(r.queryParams))
+	return r, nil
+}
+
+// matchKey reports whether key matches any of patterns
+func matchKey(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// hashValue replaces a value with a salted SHA-256 prefix, so equal values
+// still correlate without being readable
+func (r *attributeRedactor) hashValue(value string) string {
+	sum := sha256.Sum256([]byte(r.salt + value))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+// scrubQuery redacts the values of sensitive parameters in a URL, or in a
+// query string when bare is set, leaving everything else byte-for-byte intact
+func (r *attributeRedactor) scrubQuery(value string, bare bool) (string, bool) {
+	prefix, query := "", value
+	if !bare {
+		i := strings.IndexByte(value, '?')
+		if i < 0 {
+			return value, false
+		}
+		prefix, query = value[:i+1], value[i+1:]
+	}
+	query, fragment, hasFragment := strings.Cut(query, "#")
+
+	pairs := strings.Split(query, "&")
+	changed := false
+	for i, pair := range pairs {
+		name, _, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		unescaped, err := url.QueryUnescape(name)
+		if err != nil {
+			unescaped = name
+		}
+		if r.queryParams[strings.ToLower(unescaped)] {
+			pairs[i] = name + "=" + redacted
+			changed = true
+		}
+	}
+	if !changed {
+		return value, false
+	}
+	result := prefix + strings.Join(pairs, "&")
+	if hasFragment {
+		result += "#" + fragment
+	}
+	return result, true
+}
+
+// apply returns attrs with sensitive values rewritten, and whether any were
+func (r *attributeRedactor) apply(ctx context.Context, attrs []attribute.KeyValue) ([]attribute.KeyValue, bool) {
+	var out []attribute.KeyValue
+	for i, kv := range attrs {
+		key := strings.ToLower(string(kv.Key))
+		if matchKey(r.allow, key) {
+			continue
+		}
+
+		replacement, action := kv, ""
+		switch {
+		case matchKey(r.redact, key):
+			replacement, action = attribute.String(string(kv.Key), redacted), redactActionRedact
+		case matchKey(r.hash, key):
+			replacement, action = attribute.String(string(kv.Key), r.hashValue(kv.Value.Emit())), redactActionHash
+		case kv.Value.Type() == attribute.STRING:
+			bare, isURL := urlAttributeKeys[kv.Key]
+			if !isURL {
+				break
+			}
+			if scrubbed, ok := r.scrubQuery(kv.Value.AsString(), bare); ok {
+				replacement, action = attribute.String(string(kv.Key), scrubbed), redactActionQuery
+			}
+		}
+		if action == "" {
+			continue
+		}
+
+		// Copy on first change so the span's own attributes are never mutated
+		if out == nil {
+			out = append([]attribute.KeyValue(nil), attrs...)
+		}
+		out[i] = replacement
+		r.redactions.Add(ctx, 1, metric.WithAttributes(attribute.String("redaction.action", action)))
+	}
+	if out == nil {
+		return attrs, false
+	}
+	return out, true
+}
+
+// redactionProcessor sits in front of the batch span processors and hands
+// them spans whose attributes, and those of their events, have been redacted
+type redactionProcessor struct {
+	next     []sdktrace.SpanProcessor
+	redactor *attributeRedactor
+}
+
+var _ sdktrace.SpanProcessor = (*redactionProcessor)(nil)
+
+func newRedactionProcessor(next ...sdktrace.SpanProcessor) (*redactionProcessor, error) {
+	redactor, err := newAttributeRedactor()
+	if err != nil {
+		return nil, err
+	}
+	return &redactionProcessor{next: next, redactor: redactor}, nil
+}
+
+func (p *redactionProcessor) OnStart(ctx context.Context, s sdktrace.ReadWriteSpan) {
+	for _, next := range p.next {
+		next.OnStart(ctx, s)
+	}
+}
+
+func (p *redactionProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if s.SpanContext().IsSampled() {
+		s = p.redact(s)
+	}
+	for _, next := range p.next {
+		next.OnEnd(s)
+	}
+}
+
+// redact wraps s when any of its attributes needed rewriting
+func (p *redactionProcessor) redact(s sdktrace.ReadOnlySpan) sdktrace.ReadOnlySpan {
+	ctx := context.Background()
+	attrs, changed := p.redactor.apply(ctx, s.Attributes())
+
+	events := s.Events()
+	var redactedEvents []sdktrace.Event
+	for i, event := range events {
+		eventAttrs, ok := p.redactor.apply(ctx, event.Attributes)
+		if !ok {
+			continue
+		}
+		if redactedEvents == nil {
+			redactedEvents = append([]sdktrace.Event(nil), events...)
+		}
+		redactedEvents[i].Attributes = eventAttrs
+	}
+
+	if !changed && redactedEvents == nil {
+		return s
+	}
+	if redactedEvents == nil {
+		redactedEvents = events
+	}
+	return redactedSpan{ReadOnlySpan: s, attrs: attrs, events: redactedEvents}
+}
+
+func (p *redactionProcessor) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, next := range p.next {
+		if err := next.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *redactionProcessor) ForceFlush(ctx context.Context) error {
+	var errs []error
+	for _, next := range p.next {
+		if err := next.ForceFlush(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// redactedSpan presents a span with its redacted attributes and events
+type redactedSpan struct {
+	sdktrace.ReadOnlySpan
+	attrs  []attribute.KeyValue
+	events []sdktrace.Event
+}
+
+func (s redactedSpan) Attributes() []attribute.KeyValue { return s.attrs }
+
+func (s redactedSpan) Events() []sdktrace.Event { return s.events }
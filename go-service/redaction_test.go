@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestAttributeRedactor(t *testing.T) {
+	hashed := (&attributeRedactor{salt: "pepper"}).hashValue
+
+	tests := []struct {
+		name   string
+		redact string
+		hash   string
+		allow  string
+		attr   attribute.KeyValue
+		want   string
+	}{
+		{"redact by default", defaultRedactKeys, defaultHashKeys, "", attribute.String("http.request.header.authorization", "Bearer t"), redacted},
+		{"hash by default", defaultRedactKeys, defaultHashKeys, "", attribute.String("enduser.email", "a@example.com"), hashed("a@example.com")},
+		{"hash non-string values", "", "user.id", "", attribute.Int("user.id", 42), hashed("42")},
+		{"untouched", defaultRedactKeys, defaultHashKeys, "", attribute.String("http.route", "/api/orders"), "/api/orders"},
+		{"keys match case-insensitively", "Session.Token", "", "", attribute.String("session.TOKEN", "s"), redacted},
+		{"prefix pattern", "http.request.header.*", "", "", attribute.String("http.request.header.x-custom", "v"), redacted},
+		{"redact wins over hash", "user.email", "user.email", "", attribute.String("user.email", "a@example.com"), redacted},
+		{"allow wins over redact", "http.request.header.*", "", "http.request.header.accept", attribute.String("http.request.header.accept", "*/*"), "*/*"},
+		{"allow wins over hash", "", "user.*", "user.email", attribute.String("user.email", "a@example.com"), "a@example.com"},
+		{"allow wins over query scrubbing", "", "", "url.full", attribute.String("url.full", "https://x/?token=t"), "https://x/?token=t"},
+		{"query param redacted", "", "", "", attribute.String("url.full", "https://x/p?a=1&Token=t#f"), "https://x/p?a=1&Token=" + redacted + "#f"},
+		{"bare query", "", "", "", attribute.String("url.query", "code=c&page=2"), "code=" + redacted + "&page=2"},
+		{"redact wins over query scrubbing", "url.full", "", "", attribute.String("url.full", "https://x/?token=t"), redacted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ATTRIBUTE_REDACT_KEYS", tt.redact)
+			t.Setenv("ATTRIBUTE_HASH_KEYS", tt.hash)
+			t.Setenv("ATTRIBUTE_ALLOW_KEYS", tt.allow)
+			t.Setenv("ATTRIBUTE_HASH_SALT", "pepper")
+			r, err := newAttributeRedactor()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			attrs := []attribute.KeyValue{tt.attr}
+			got, changed := r.apply(context.Background(), attrs)
+			if got[0].Value.Emit() != tt.want {
+				t.Errorf("%s = %q, want %q", tt.attr.Key, got[0].Value.Emit(), tt.want)
+			}
+			if wantChanged := tt.want != tt.attr.Value.Emit(); changed != wantChanged {
+				t.Errorf("changed = %t, want %t", changed, wantChanged)
+			}
+			if attrs[0] != tt.attr {
+				t.Error("apply mutated its input")
+			}
+		})
+	}
+}
+
+func TestRedactionProcessor(t *testing.T) {
+	t.Setenv("ATTRIBUTE_REDACT_KEYS", "secret")
+	recorder := tracetest.NewSpanRecorder()
+	processor, err := newRedactionProcessor(recorder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(processor))
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	_, span := provider.Tracer("test").Start(context.Background(), "span")
+	span.AddEvent("event", trace.WithAttributes(attribute.String("secret", "event value")))
+	span.SetAttributes(attribute.String("secret", "span value"), attribute.String("public", "p"))
+	span.End()
+
+	ended := recorder.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("%d spans exported, want 1", len(ended))
+	}
+	values := make(map[attribute.Key]string)
+	for _, attr := range ended[0].Attributes() {
+		values[attr.Key] = attr.Value.AsString()
+	}
+	if values["secret"] != redacted || values["public"] != "p" {
+		t.Errorf("span attributes = %v, want only secret redacted", values)
+	}
+	if got := ended[0].Events()[0].Attributes[0].Value.AsString(); got != redacted {
+		t.Errorf("event attribute secret = %q, want it redacted", got)
+	}
+}
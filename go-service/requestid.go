@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// requestIDHeader carries a caller-assigned request ID, for systems that
+// correlate by request rather than by trace
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds an incoming request ID; longer or non-printable
+// values are replaced rather than copied into spans and logs
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// requestID returns the request's X-Request-ID when it is a usable value, and
+// otherwise a new random UUID
+func requestID(r *http.Request) string {
+	if id := r.Header.Get(requestIDHeader); validRequestID(id) {
+		return id
+	}
+	return uuid.NewString()
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func withRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// requestIDFrom returns the request ID stored on ctx by the tracing
+// middleware, or "" outside a request
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestIDTransport passes the request ID on to downstream calls, so one ID
+// follows the request across services the way the trace context does
+type requestIDTransport struct {
+	next http.RoundTripper
+}
+
+func (t *requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id := requestIDFrom(req.Context())
+	if id == "" || req.Header.Get(requestIDHeader) != "" {
+		return t.next.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set(requestIDHeader, id)
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// Service identity, overridable via OTEL_SERVICE_NAME and SERVICE_VERSION so
+// several differently-named instances can run from one image
+var (
+	serviceName    = "go-service"
+	serviceVersion = "1.0.0"
+)
+
+// loadServiceIdentity reads the service identity from the environment
+func loadServiceIdentity() {
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" {
+		serviceName = name
+	}
+	if version := os.Getenv("SERVICE_VERSION"); version != "" {
+		serviceVersion = version
+	}
+}
+
+// newResource builds the resource shared by the tracer, meter, and logger providers
+func newResource(ctx context.Context) (*resource.Resource, error) {
+	attrs := []attribute.KeyValue{
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(serviceVersion),
+	}
+	if env := os.Getenv("DEPLOYMENT_ENV"); env != "" {
+		attrs = append(attrs, semconv.DeploymentEnvironment(env))
+	}
+	attrs = append(attrs, kubernetesAttributes()...)
+	attrs = append(attrs, build.attributes()...)
+	attrs = append(attrs, unixSocketAttributes()...)
+
+	// Detect process, host, OS, and container attributes so backends can
+	// correlate entities; OTEL_RESOURCE_ATTRIBUTES is applied last and wins
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithProcess(),
+		resource.WithHost(),
+		resource.WithOS(),
+		resource.WithContainer(),
+		resource.WithAttributes(attrs...),
+		resource.WithFromEnv(),
+	)
+	if errors.Is(err, resource.ErrPartialResource) {
+		// Some detectors failed (e.g. no container ID outside a container); keep the rest
+		log.Printf("Partial resource detected: %v", err)
+		return res, nil
+	}
+	return res, err
+}
+
+// kubernetesAttributes returns k8s.* attributes from env vars injected via the
+// Kubernetes downward API (see k8s/go-service.yaml)
+func kubernetesAttributes() []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if name := os.Getenv("K8S_POD_NAME"); name != "" {
+		attrs = append(attrs, semconv.K8SPodName(name))
+	}
+	if uid := os.Getenv("K8S_POD_UID"); uid != "" {
+		attrs = append(attrs, semconv.K8SPodUID(uid))
+	}
+	if namespace := os.Getenv("K8S_NAMESPACE_NAME"); namespace != "" {
+		attrs = append(attrs, semconv.K8SNamespaceName(namespace))
+	}
+	if node := os.Getenv("K8S_NODE_NAME"); node != "" {
+		attrs = append(attrs, semconv.K8SNodeName(node))
+	}
+	return attrs
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Outbound retry policy from RETRY_MAX_ATTEMPTS, RETRY_BACKOFF, and RETRY_MAX_BACKOFF
+var (
+	retryMaxAttempts int
+	retryBackoff     time.Duration
+	retryMaxBackoff  time.Duration
+)
+
+var outboundRetries metric.Int64Counter
+
+// initRetry configures retries of outbound calls: up to RETRY_MAX_ATTEMPTS
+// attempts in total (default 3; 1 disables retrying) with exponential backoff
+// from RETRY_BACKOFF (100ms) capped at RETRY_MAX_BACKOFF (2s), with jitter
+func initRetry() error {
+	retryMaxAttempts = max(getEnvInt("RETRY_MAX_ATTEMPTS", 3), 1)
+	retryBackoff = max(getEnvDuration("RETRY_BACKOFF", 100*time.Millisecond), 0)
+	retryMaxBackoff = max(getEnvDuration("RETRY_MAX_BACKOFF", 2*time.Second), 0)
+
+	var err error
+	outboundRetries, err = meter.Int64Counter(
+		"http.client.retries",
+		metric.WithDescription("The number of outbound HTTP attempts that were retries of an earlier failed attempt"),
+		metric.WithUnit("{retry}"),
+	)
+	if err != nil {
+		return err
+	}
+	if retryMaxAttempts > 1 {
+		log.Printf("Outbound retries enabled: %d attempts, backoff %s up to %s", retryMaxAttempts, retryBackoff, retryMaxBackoff)
+	}
+	return nil
+}
+
+// retryable reports whether a request may be sent again: idempotent methods,
+// or any method carrying an Idempotency-Key, with a body that can be replayed
+func retryable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get(idempotencyHeader) != ""
+}
+
+// retryReason classifies a failed attempt as transient, returning "" when it
+// should not be retried. Circuit-open errors and our own cancellation are
+// final; transport errors and 429/502/503/504 responses are transient.
+func retryReason(ctx context.Context, resp *http.Response, err error) string {
+	if ctx.Err() != nil || errors.Is(err, errCircuitOpen) {
+		return ""
+	}
+	if err != nil {
+		return downstreamErrorType(err)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return strconv.Itoa(resp.StatusCode)
+	}
+	return ""
+}
+
+// backoffFor returns the delay before retry number n (1-based): exponential
+// with full jitter, or the server's Retry-After when it asks for longer
+func backoffFor(n int, resp *http.Response) time.Duration {
+	ceiling := min(retryBackoff<<(n-1), retryMaxBackoff)
+	delay := time.Duration(rand.Int63n(int64(ceiling) + 1))
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			delay = max(delay, min(time.Duration(seconds)*time.Second, retryMaxBackoff))
+		}
+	}
+	return delay
+}
+
+// retryTransport retries transient failures of outbound calls. Each attempt
+// runs in its own child span carrying the attempt number, so the CLIENT span
+// of every attempt (and the backoff between them) is visible in the trace.
+type retryTransport struct {
+	next http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	attempts := 1
+	if retryable(req) {
+		attempts = retryMaxAttempts
+	}
+
+	var backoff time.Duration
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(ctx)
+			attemptReq.Body = body
+		}
+
+		resp, reason, err := t.attempt(attemptReq, attempt, backoff)
+		if reason == "" || attempt >= attempts {
+			trace.SpanFromContext(ctx).SetAttributes(attribute.Int("http.client.attempts", attempt))
+			return resp, err
+		}
+
+		// Discard the failed response before trying again
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		outboundRetries.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("server.address", req.URL.Hostname()),
+			attribute.String("retry.reason", reason),
+		))
+		backoff = backoffFor(attempt, resp)
+		if err := sleepContext(ctx, backoff); err != nil {
+			trace.SpanFromContext(ctx).SetAttributes(attribute.Int("http.client.attempts", attempt))
+			return nil, err
+		}
+	}
+}
+
+// attempt sends one attempt inside its own span, returning why it should be
+// retried, if it should
+func (t *retryTransport) attempt(req *http.Request, attempt int, backoff time.Duration) (*http.Response, string, error) {
+	attrs := []attribute.KeyValue{
+		attribute.Int("retry.attempt", attempt),
+		attribute.String("server.address", req.URL.Hostname()),
+	}
+	if attempt > 1 {
+		attrs = append(attrs,
+			attribute.Int("http.request.resend_count", attempt-1),
+			attribute.Int64("retry.backoff_ms", backoff.Milliseconds()),
+		)
+	}
+	ctx, span := tracer.Start(req.Context(), "http.client.attempt", trace.WithAttributes(attrs...))
+	defer span.End()
+
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	reason := retryReason(req.Context(), resp, err)
+	switch {
+	case err != nil:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	case resp.StatusCode >= http.StatusInternalServerError:
+		span.SetStatus(codes.Error, "HTTP "+strconv.Itoa(resp.StatusCode))
+	}
+	if reason != "" {
+		span.SetAttributes(attribute.String("retry.reason", reason))
+	}
+	return resp, reason, err
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		key       string
+		body      string
+		statuses  []int
+		wantCalls int
+		want      int
+	}{
+		{"GET retries until success", http.MethodGet, "", "", []int{503, 502, 200}, 3, http.StatusOK},
+		{"GET gives up after max attempts", http.MethodGet, "", "", []int{503, 503, 503, 200}, 3, http.StatusServiceUnavailable},
+		{"GET is not retried on 500", http.MethodGet, "", "", []int{500, 200}, 1, http.StatusInternalServerError},
+		{"GET is not retried on 404", http.MethodGet, "", "", []int{404, 200}, 1, http.StatusNotFound},
+		{"429 is retried", http.MethodGet, "", "", []int{429, 200}, 2, http.StatusOK},
+		{"PUT is retried", http.MethodPut, "", "a", []int{503, 200}, 2, http.StatusOK},
+		{"POST is not retried", http.MethodPost, "", "a", []int{503, 200}, 1, http.StatusServiceUnavailable},
+		{"PATCH is not retried", http.MethodPatch, "", "a", []int{503, 200}, 1, http.StatusServiceUnavailable},
+		{"POST with an idempotency key is retried", http.MethodPost, "k1", "a", []int{503, 200}, 2, http.StatusOK},
+	}
+	useRetryPolicy(t, "3", "1ms", "2ms")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			var bodies []string
+			transport := &retryTransport{next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				if req.Body != nil {
+					body, err := io.ReadAll(req.Body)
+					if err != nil {
+						t.Fatal(err)
+					}
+					bodies = append(bodies, string(body))
+				}
+				rec := httptest.NewRecorder()
+				rec.WriteHeader(tt.statuses[calls])
+				calls++
+				return rec.Result(), nil
+			})}
+
+			req, err := http.NewRequest(tt.method, "http://downstream/api", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.key != "" {
+				req.Header.Set(idempotencyHeader, tt.key)
+			}
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if calls != tt.wantCalls {
+				t.Errorf("attempts = %d, want %d", calls, tt.wantCalls)
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+			for i, body := range bodies {
+				if body != tt.body {
+					t.Errorf("attempt %d sent body %q, want %q", i+1, body, tt.body)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	// Server requests have no GetBody to replay the body with
+	unreplayable := httptest.NewRequest(http.MethodPut, "http://downstream/api", strings.NewReader("a"))
+	if retryable(unreplayable) {
+		t.Error("a request whose body cannot be replayed was retryable")
+	}
+}
+
+func TestBackoffFor(t *testing.T) {
+	useRetryPolicy(t, "5", "100ms", "250ms")
+	retryAfter := func(value string) *http.Response {
+		rec := httptest.NewRecorder()
+		rec.Header().Set("Retry-After", value)
+		rec.WriteHeader(http.StatusServiceUnavailable)
+		return rec.Result()
+	}
+
+	tests := []struct {
+		name     string
+		n        int
+		resp     *http.Response
+		min, max time.Duration
+	}{
+		{"first retry within base", 1, nil, 0, 100 * time.Millisecond},
+		{"second retry doubles", 2, nil, 0, 200 * time.Millisecond},
+		{"capped at max backoff", 4, nil, 0, 250 * time.Millisecond},
+		{"Retry-After is honoured up to the cap", 1, retryAfter("1"), 250 * time.Millisecond, 250 * time.Millisecond},
+		{"Retry-After of zero keeps the jitter", 1, retryAfter("0"), 0, 100 * time.Millisecond},
+		{"HTTP-date Retry-After is ignored", 1, retryAfter("Wed, 21 Oct 2015 07:28:00 GMT"), 0, 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 50 {
+				if got := backoffFor(tt.n, tt.resp); got < tt.min || got > tt.max {
+					t.Fatalf("backoffFor(%d) = %s, want between %s and %s", tt.n, got, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+// useRetryPolicy configures the retry policy for the test
+func useRetryPolicy(t *testing.T, attempts, backoff, maxBackoff string) {
+	t.Helper()
+	t.Setenv("RETRY_MAX_ATTEMPTS", attempts)
+	t.Setenv("RETRY_BACKOFF", backoff)
+	t.Setenv("RETRY_MAX_BACKOFF", maxBackoff)
+	savedAttempts, savedBackoff, savedMax := retryMaxAttempts, retryBackoff, retryMaxBackoff
+	t.Cleanup(func() { retryMaxAttempts, retryBackoff, retryMaxBackoff = savedAttempts, savedBackoff, savedMax })
+	if err := initRetry(); err != nil {
+		t.Fatal(err)
+	}
+}
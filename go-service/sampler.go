@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceSampler is the tracer provider's sampler, reconfigurable at runtime
+// through /internal/admin
+var traceSampler *dynamicSampler
+
+// dynamicSampler delegates to a sampler that can be swapped while the tracer
+// provider is running, remembering the OTEL_TRACES_SAMPLER name and ratio it
+// was built from
+type dynamicSampler struct {
+	mu      sync.RWMutex
+	name    string
+	ratio   float64
+	sampler sdktrace.Sampler
+}
+
+// newSampler builds the sampler an OTEL_TRACES_SAMPLER name refers to
+func newSampler(name string, ratio float64) (sdktrace.Sampler, error) {
+	switch name {
+	case "always_on":
+		return sdktrace.AlwaysSample(), nil
+	case "always_off":
+		return sdktrace.NeverSample(), nil
+	case "traceidratio":
+		return sdktrace.TraceIDRatioBased(ratio), nil
+	case "parentbased_always_on":
+		return sdktrace.ParentBased(sdktrace.AlwaysSample()), nil
+	case "parentbased_always_off":
+		return sdktrace.ParentBased(sdktrace.NeverSample()), nil
+	case "parentbased_traceidratio":
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio)), nil
+	case "error_biased":
+		return newErrorBiasedSampler(ratio), nil
+	}
+	return nil, fmt.Errorf("unsupported sampler %q", name)
+}
+
+// Set replaces the delegate with the named sampler
+func (s *dynamicSampler) Set(name string, ratio float64) error {
+	sampler, err := newSampler(name, ratio)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.name, s.ratio, s.sampler = name, ratio, sampler
+	return nil
+}
+
+// Config returns the sampler name and ratio currently in effect
+func (s *dynamicSampler) Config() (string, float64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.name, s.ratio
+}
+
+func (s *dynamicSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	s.mu.RLock()
+	sampler := s.sampler
+	s.mu.RUnlock()
+	return sampler.ShouldSample(p)
+}
+
+func (s *dynamicSampler) Description() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sampler.Description()
+}
+
+// routeSamplingRules maps http.route to the sampling ratio its server spans
+// use instead of the tracer provider's sampler, from OTEL_TRACES_SAMPLER_ROUTES
+var routeSamplingRules map[string]float64
+
+// loadRouteSamplingRules parses OTEL_TRACES_SAMPLER_ROUTES, a comma-separated
+// list of route=ratio pairs such as "/health=0,/api/compute=0.25"
+func loadRouteSamplingRules() map[string]float64 {
+	rules := make(map[string]float64)
+	for _, pair := range splitList(os.Getenv("OTEL_TRACES_SAMPLER_ROUTES")) {
+		route, value, ok := strings.Cut(pair, "=")
+		ratio, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if !ok || err != nil || ratio < 0 || ratio > 1 {
+			log.Printf("Ignoring invalid OTEL_TRACES_SAMPLER_ROUTES entry %q; want route=ratio with a ratio between 0 and 1", pair)
+			continue
+		}
+		rules[strings.TrimSpace(route)] = ratio
+	}
+	return rules
+}
+
+// routeSampler applies per-route sampling rules to server spans, matched on
+// the http.route attribute they are started with, and defers to next for
+// everything else. A ratio of 0 drops the route's spans even under a sampled
+// parent, so probes never reach the backend; other ratios sample new traces
+// at that ratio and follow the parent's decision otherwise. Spans below a
+// dropped server span are then dropped by the parent-based samplers.
+type routeSampler struct {
+	rules map[string]sdktrace.Sampler
+	next  sdktrace.Sampler
+}
+
+func newRouteSampler(next sdktrace.Sampler, ratios map[string]float64) sdktrace.Sampler {
+	if len(ratios) == 0 {
+		return next
+	}
+	rules := make(map[string]sdktrace.Sampler, len(ratios))
+	for route, ratio := range ratios {
+		if ratio == 0 {
+			rules[route] = sdktrace.NeverSample()
+		} else {
+			rules[route] = sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))
+		}
+	}
+	return &routeSampler{rules: rules, next: next}
+}
+
+func (s *routeSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	if p.Kind == trace.SpanKindServer {
+		for _, kv := range p.Attributes {
+			if kv.Key != "http.route" {
+				continue
+			}
+			if rule, ok := s.rules[kv.Value.AsString()]; ok {
+				return rule.ShouldSample(p)
+			}
+			break
+		}
+	}
+	return s.next.ShouldSample(p)
+}
+
+func (s *routeSampler) Description() string {
+	routes := slices.Sorted(maps.Keys(s.rules))
+	rules := make([]string, len(routes))
+	for i, route := range routes {
+		rules[i] = route + ":" + s.rules[route].Description()
+	}
+	return fmt.Sprintf("RouteBased{%s,default:%s}", strings.Join(rules, ","), s.next.Description())
+}
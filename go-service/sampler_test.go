@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRouteSampler(t *testing.T) {
+	sampledParent := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	}))
+	sampler := newRouteSampler(sdktrace.AlwaysSample(), map[string]float64{"/healthz": 0, "/api/compute": 1})
+
+	tests := []struct {
+		name   string
+		parent context.Context
+		kind   trace.SpanKind
+		route  string
+		want   sdktrace.SamplingDecision
+	}{
+		{"ratio 0 drops the route", context.Background(), trace.SpanKindServer, "/healthz", sdktrace.Drop},
+		{"ratio 0 drops under a sampled parent", sampledParent, trace.SpanKindServer, "/healthz", sdktrace.Drop},
+		{"ratio 1 samples the route", context.Background(), trace.SpanKindServer, "/api/compute", sdktrace.RecordAndSample},
+		{"unmatched route uses the default", context.Background(), trace.SpanKindServer, "/api/orders", sdktrace.RecordAndSample},
+		{"client spans use the default", context.Background(), trace.SpanKindClient, "/healthz", sdktrace.RecordAndSample},
+		{"no route uses the default", context.Background(), trace.SpanKindServer, "", sdktrace.RecordAndSample},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := sdktrace.SamplingParameters{ParentContext: tt.parent, TraceID: trace.TraceID{1}, Name: "span", Kind: tt.kind}
+			if tt.route != "" {
+				p.Attributes = []attribute.KeyValue{attribute.String("http.route", tt.route)}
+			}
+			if got := sampler.ShouldSample(p).Decision; got != tt.want {
+				t.Errorf("decision = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRouteSamplerUsesNeverSample(t *testing.T) {
+	sampler, ok := newRouteSampler(sdktrace.AlwaysSample(), map[string]float64{"/healthz": 0}).(*routeSampler)
+	if !ok {
+		t.Fatal("newRouteSampler did not return a routeSampler")
+	}
+	if got, want := sampler.rules["/healthz"].Description(), sdktrace.NeverSample().Description(); got != want {
+		t.Errorf("ratio 0 rule = %s, want %s", got, want)
+	}
+	if got := newRouteSampler(sdktrace.AlwaysSample(), nil); got.Description() != sdktrace.AlwaysSample().Description() {
+		t.Errorf("without rules the sampler is %s, want the default", got.Description())
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const reconciliationJob = "inventory-reconciliation"
+
+var (
+	scheduledRuns        metric.Int64Counter
+	scheduledDuration    metric.Float64Histogram
+	inventoryDiscrepancy metric.Int64Counter
+)
+
+// startScheduler runs the inventory reconciliation job every
+// RECONCILE_INTERVAL (default 30s, 0 disables) so the pipeline carries
+// baseline traces and metrics even with no inbound traffic. The returned stop
+// function ends the schedule, waiting for a run in progress, so no new traces
+// start while the providers shut down.
+func startScheduler() (stop func(), err error) {
+	interval := getEnvDuration("RECONCILE_INTERVAL", 30*time.Second)
+	if interval <= 0 {
+		return func() {}, nil
+	}
+
+	scheduledRuns, err = meter.Int64Counter(
+		"scheduled_job.runs",
+		metric.WithDescription("The number of scheduled job runs by outcome"),
+		metric.WithUnit("{run}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduledDuration, err = meter.Float64Histogram(
+		"scheduled_job.duration",
+		metric.WithDescription("Duration of scheduled job runs"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	inventoryDiscrepancy, err = meter.Int64Counter(
+		"inventory.discrepancies",
+		metric.WithDescription("Order lines whose stock did not match during reconciliation"),
+		metric.WithUnit("{item}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				runReconciliation()
+			}
+		}
+	}()
+	log.Printf("Scheduled %s every %s", reconciliationJob, interval)
+	return func() {
+		cancel()
+		<-done
+	}, nil
+}
+
+// runReconciliation performs one reconciliation pass in its own root trace:
+// it reads order totals from the database and compares them against a
+// simulated warehouse count
+func runReconciliation() {
+	start := time.Now()
+	ctx, span := tracer.Start(context.Background(), reconciliationJob,
+		trace.WithNewRoot(),
+		trace.WithAttributes(attribute.String("job.name", reconciliationJob)),
+	)
+	defer span.End()
+
+	outcome := "success"
+	checked, discrepancies, err := reconcileInventory(ctx)
+	if err != nil {
+		outcome = "failure"
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		logger.ErrorContext(ctx, "Inventory reconciliation failed", "error", err)
+	} else {
+		span.SetAttributes(
+			attribute.Int("inventory.items_checked", checked),
+			attribute.Int("inventory.discrepancies", discrepancies),
+		)
+		logger.InfoContext(ctx, "Inventory reconciliation completed",
+			"inventory.items_checked", checked,
+			"inventory.discrepancies", discrepancies,
+		)
+	}
+
+	attrs := metric.WithAttributes(
+		attribute.String("job.name", reconciliationJob),
+		attribute.String("job.outcome", outcome),
+	)
+	scheduledRuns.Add(ctx, 1, attrs)
+	scheduledDuration.Record(ctx, time.Since(start).Seconds(), attrs)
+}
+
+// reconcileInventory compares per-item order quantities with a simulated
+// warehouse count, which is occasionally off by a few units
+func reconcileInventory(ctx context.Context) (checked, discrepancies int, err error) {
+	totals, err := loadOrderTotals(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	_, span := tracer.Start(ctx, "compare-warehouse-stock")
+	defer span.End()
+	for item, ordered := range totals {
+		checked++
+		if rand.Float64() < 0.05 {
+			discrepancies++
+			span.AddEvent("inventory.discrepancy", trace.WithAttributes(
+				attribute.String("inventory.item", item),
+				attribute.Int("inventory.ordered", ordered),
+				attribute.Int("inventory.counted", ordered-rand.Intn(3)-1),
+			))
+		}
+	}
+	if discrepancies > 0 {
+		inventoryDiscrepancy.Add(ctx, int64(discrepancies))
+	}
+	return checked, discrepancies, nil
+}
+
+// loadOrderTotals sums ordered quantities per item
+func loadOrderTotals(ctx context.Context) (map[string]int, error) {
+	ctx, span := tracer.Start(ctx, "load-order-totals")
+	defer span.End()
+
+	rows, err := db.QueryContext(ctx, `SELECT item, SUM(quantity) FROM orders GROUP BY item`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := make(map[string]int)
+	for rows.Next() {
+		var item string
+		var quantity int
+		if err := rows.Scan(&item, &quantity); err != nil {
+			return nil, err
+		}
+		totals[item] = quantity
+	}
+	span.SetAttributes(attribute.Int("inventory.items", len(totals)))
+	return totals, rows.Err()
+}
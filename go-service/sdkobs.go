@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http/httptrace"
+	"os"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// sdkSelfObservabilityEnv is the SDK's experimental switch for its own
+// metrics, including otel.sdk.processor.span.processed, whose
+// error.type=queue_full series counts spans the batch processor dropped
+const sdkSelfObservabilityEnv = "OTEL_GO_X_SELF_OBSERVABILITY"
+
+var (
+	exportItems    metric.Int64Counter
+	exportFailures metric.Int64Counter
+	exportRetries  metric.Int64Counter
+)
+
+// initSDKObservability turns on the SDK's self-observability metrics (unless
+// SDK_SELF_OBSERVABILITY=false or OTEL_GO_X_SELF_OBSERVABILITY is set) and
+// creates the exporter counters recorded by the tracked OTLP exporters. It
+// runs before the providers exist, so instruments come from the global
+// meter, which starts recording once the meter provider is installed.
+func initSDKObservability() error {
+	if _, ok := os.LookupEnv(sdkSelfObservabilityEnv); !ok && getEnvBool("SDK_SELF_OBSERVABILITY", true) {
+		if err := os.Setenv(sdkSelfObservabilityEnv, "true"); err != nil {
+			return err
+		}
+	}
+	log.Printf("SDK self-observability metrics: %s=%s", sdkSelfObservabilityEnv, os.Getenv(sdkSelfObservabilityEnv))
+
+	m := otel.Meter("go-service")
+	var err error
+	exportItems, err = m.Int64Counter(
+		"telemetry.exporter.items",
+		metric.WithDescription("Spans, metric streams, and log records handed to OTLP exporters, by signal and outcome"),
+		metric.WithUnit("{item}"),
+	)
+	if err != nil {
+		return err
+	}
+
+	exportFailures, err = m.Int64Counter(
+		"telemetry.exporter.failures",
+		metric.WithDescription("OTLP export calls that failed after any retries, by signal and error type"),
+		metric.WithUnit("{export}"),
+	)
+	if err != nil {
+		return err
+	}
+
+	exportRetries, err = m.Int64Counter(
+		"telemetry.exporter.retries",
+		metric.WithDescription("Requests OTLP exporters re-sent within a single export because an earlier attempt failed"),
+		metric.WithUnit("{retry}"),
+	)
+	return err
+}
+
+type exportAttemptsKey struct{}
+
+// withExportAttempts counts the requests an exporter makes under ctx: the
+// HTTP exporters' requests through an httptrace hook, and the gRPC
+// exporters' calls through countExportAttempts
+func withExportAttempts(ctx context.Context) (context.Context, *atomic.Int64) {
+	attempts := new(atomic.Int64)
+	ctx = context.WithValue(ctx, exportAttemptsKey{}, attempts)
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn: func(string) { attempts.Add(1) },
+	})
+	return ctx, attempts
+}
+
+// countExportAttempts is a gRPC client interceptor for the OTLP exporters
+func countExportAttempts(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if attempts, ok := ctx.Value(exportAttemptsKey{}).(*atomic.Int64); ok {
+		attempts.Add(1)
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// exportErrorType classifies a failed export for the error.type attribute.
+// The processors give up with their own cause, so the context is consulted too.
+func exportErrorType(ctx context.Context, err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled), errors.Is(ctx.Err(), context.Canceled):
+		return "canceled"
+	}
+	if s, ok := status.FromError(err); ok && s.Code() != codes.Unknown {
+		return s.Code().String()
+	}
+	return "export_failed"
+}
+
+// recordExport records the outcome of one export call for signal
+func recordExport(ctx context.Context, signal string, items int, attempts int64, err error) {
+	if exportItems == nil {
+		return
+	}
+	// The export context may already be done; the measurements still count
+	recordCtx := context.WithoutCancel(ctx)
+
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+		exportFailures.Add(recordCtx, 1, metric.WithAttributes(
+			attribute.String("telemetry.signal", signal),
+			attribute.String("error.type", exportErrorType(ctx, err)),
+		))
+	}
+	exportItems.Add(recordCtx, int64(items), metric.WithAttributes(
+		attribute.String("telemetry.signal", signal),
+		attribute.String("telemetry.export.outcome", outcome),
+	))
+	if attempts > 1 {
+		exportRetries.Add(recordCtx, attempts-1, metric.WithAttributes(
+			attribute.String("telemetry.signal", signal),
+		))
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// newHTTPServer builds the API server with timeouts from HTTP_READ_HEADER_TIMEOUT,
+// HTTP_READ_TIMEOUT, HTTP_WRITE_TIMEOUT, and HTTP_IDLE_TIMEOUT (durations such
+// as "30s"; 0 disables). Streaming handlers clear the write deadline themselves.
+// HTTP2_ENABLED and H2C_ENABLED (both default true) control HTTP/2.
+func newHTTPServer(addr string) *http.Server {
+	server := &http.Server{
+		Addr:         addr,
+		WriteTimeout: getEnvDuration("HTTP_WRITE_TIMEOUT", 60*time.Second),
+		ConnContext:  withConnTransport,
+	}
+	setReadTimeouts(server)
+	if connections != nil {
+		server.ConnState = connections.track
+	}
+	log.Printf("HTTP server timeouts: read_header=%s read=%s write=%s idle=%s",
+		server.ReadHeaderTimeout, server.ReadTimeout, server.WriteTimeout, server.IdleTimeout)
+
+	// HTTP/2 is negotiated over TLS by ALPN; h2c also serves it in cleartext
+	// to clients using prior knowledge or Upgrade: h2c
+	switch {
+	case !getEnvBool("HTTP2_ENABLED", true):
+		server.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+		log.Printf("HTTP/2 disabled")
+	case getEnvBool("H2C_ENABLED", true):
+		server.Handler = h2c.NewHandler(http.DefaultServeMux, &http2.Server{IdleTimeout: server.IdleTimeout})
+		log.Printf("HTTP/2 enabled, including cleartext h2c")
+	default:
+		log.Printf("HTTP/2 enabled over TLS only")
+	}
+	return server
+}
+
+// setReadTimeouts applies HTTP_READ_HEADER_TIMEOUT, HTTP_READ_TIMEOUT, and
+// HTTP_IDLE_TIMEOUT, which bound slow or idle clients on every server
+func setReadTimeouts(server *http.Server) {
+	server.ReadHeaderTimeout = getEnvDuration("HTTP_READ_HEADER_TIMEOUT", 10*time.Second)
+	server.ReadTimeout = getEnvDuration("HTTP_READ_TIMEOUT", 30*time.Second)
+	server.IdleTimeout = getEnvDuration("HTTP_IDLE_TIMEOUT", 120*time.Second)
+}
+
+// isTimeout reports whether err came from a connection deadline firing
+func isTimeout(err error) bool {
+	return errors.Is(err, os.ErrDeadlineExceeded)
+}
+
+// recordTimeout adds an http.server.timeout span event naming which server
+// timeout (read or write) cut the request short
+func recordTimeout(span trace.Span, kind string, err error) {
+	span.AddEvent("http.server.timeout", trace.WithAttributes(
+		attribute.String("http.server.timeout.kind", kind),
+		attribute.String("error.message", err.Error()),
+	))
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// serverTiming collects phase durations for a request's Server-Timing header
+type serverTiming struct {
+	mu     sync.Mutex
+	start  time.Time
+	span   trace.SpanContext
+	phases []timingPhase
+}
+
+type timingPhase struct {
+	name string
+	dur  time.Duration
+}
+
+type serverTimingKey struct{}
+
+// withServerTiming attaches a Server-Timing collector for the request served
+// by the span in sc
+func withServerTiming(ctx context.Context, sc trace.SpanContext, start time.Time) (context.Context, *serverTiming) {
+	st := &serverTiming{start: start, span: sc}
+	return context.WithValue(ctx, serverTimingKey{}, st), st
+}
+
+// recordServerTiming adds a phase (e.g. "cache", "compute") to the request's
+// Server-Timing header; it is a no-op outside an HTTP request
+func recordServerTiming(ctx context.Context, name string, d time.Duration) {
+	st, ok := ctx.Value(serverTimingKey{}).(*serverTiming)
+	if !ok {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.phases = append(st.phases, timingPhase{name: name, dur: d})
+}
+
+// header renders the Server-Timing value: the traceparent of the server span
+// (so browser and synthetic clients can find the trace), each recorded phase,
+// and the total time until the headers were written
+func (st *serverTiming) header() string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var entries []string
+	if st.span.IsValid() {
+		entries = append(entries, fmt.Sprintf(`traceparent;desc="%s"`, traceparent(st.span)))
+	}
+	for _, phase := range st.phases {
+		entries = append(entries, timingEntry(phase.name, phase.dur))
+	}
+	entries = append(entries, timingEntry("total", time.Since(st.start)))
+	return strings.Join(entries, ", ")
+}
+
+// timingEntry formats one Server-Timing metric with its duration in milliseconds
+func timingEntry(name string, d time.Duration) string {
+	return fmt.Sprintf("%s;dur=%.3f", name, float64(d.Microseconds())/1000)
+}
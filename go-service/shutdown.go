@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// shutdownTimeout bounds draining in-flight requests and, separately, shutting
+// down the telemetry providers (SHUTDOWN_TIMEOUT)
+var shutdownTimeout time.Duration
+
+var serviceStopping metric.Int64Counter
+
+func initShutdown() error {
+	shutdownTimeout = getEnvDuration("SHUTDOWN_TIMEOUT", 10*time.Second)
+
+	var err error
+	serviceStopping, err = meter.Int64Counter(
+		"service.stopping",
+		metric.WithDescription("Incremented once as the service begins shutting down, so the final export records the stop"),
+		metric.WithUnit("{shutdown}"),
+	)
+	return err
+}
+
+// exportItemUnits names what each signal's export items are
+var exportItemUnits = map[string]string{
+	"traces":  "spans",
+	"metrics": "metric streams",
+	"logs":    "log records",
+}
+
+// shutdownTelemetry records the stop as a final log and metric, then shuts the
+// trace, meter, and logger providers down in parallel within shutdownTimeout,
+// so one slow collector cannot starve the others. It reports the items each
+// signal failed to export meanwhile. The SDK drops items still queued when the
+// deadline passes without saying how many, so for a signal that timed out the
+// report says its count is incomplete.
+func shutdownTelemetry(reason string) {
+	ctx := context.Background()
+	uptime := time.Since(startup.start)
+	serviceStopping.Add(ctx, 1, metric.WithAttributes(attribute.String("shutdown.reason", reason)))
+	logger.InfoContext(ctx, "Service stopping",
+		"shutdown.reason", reason,
+		"shutdown.timeout_ms", shutdownTimeout.Milliseconds(),
+		"uptime_ms", uptime.Milliseconds(),
+	)
+
+	failedBefore := failedExportItems()
+	ctx, cancel := context.WithTimeout(ctx, shutdownTimeout)
+	defer cancel()
+
+	shutdowns := map[string]func(context.Context) error{
+		"traces":  tracerProvider.Shutdown,
+		"metrics": meterProvider.Shutdown,
+		"logs":    loggerProvider.Shutdown,
+	}
+	var (
+		wg         sync.WaitGroup
+		timedOutMu sync.Mutex
+		timedOut   = make(map[string]bool)
+	)
+	for signal, shutdown := range shutdowns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := shutdown(ctx)
+			elapsed := time.Since(start).Round(time.Millisecond)
+			switch {
+			case errors.Is(err, context.DeadlineExceeded):
+				timedOutMu.Lock()
+				timedOut[signal] = true
+				timedOutMu.Unlock()
+				log.Printf("Shutdown of %s provider timed out after %s; queued items were dropped", signal, elapsed)
+			case err != nil:
+				log.Printf("Error shutting down %s provider: %v", signal, err)
+			default:
+				log.Printf("Shut down %s provider in %s", signal, elapsed)
+			}
+		}()
+	}
+	wg.Wait()
+
+	failedAfter := failedExportItems()
+	for _, signal := range []string{"traces", "metrics", "logs"} {
+		lost := failedAfter[signal] - failedBefore[signal]
+		switch {
+		case timedOut[signal]:
+			log.Printf("%d %s could not be exported during shutdown, plus an uncounted number still queued at the deadline", lost, exportItemUnits[signal])
+		case lost > 0:
+			log.Printf("%d %s could not be exported during shutdown", lost, exportItemUnits[signal])
+		}
+	}
+	log.Printf("Service stopped after %s (%s)", uptime.Round(time.Second), reason)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// slowRequestThreshold is the request duration (SLOW_REQUEST_THRESHOLD) above
+// which a request is flagged as slow; 0 disables the check
+var slowRequestThreshold time.Duration
+
+var slowRequests metric.Int64Counter
+
+func initSlowRequests() error {
+	slowRequestThreshold = getEnvDuration("SLOW_REQUEST_THRESHOLD", time.Second)
+
+	var err error
+	slowRequests, err = meter.Int64Counter(
+		"http.server.slow_requests",
+		metric.WithDescription("The number of HTTP requests that took longer than SLOW_REQUEST_THRESHOLD"),
+		metric.WithUnit("{request}"),
+	)
+	return err
+}
+
+// recordSlowRequest adds a slow_request event to the server span and counts
+// the request when elapsed exceeds the threshold
+func recordSlowRequest(ctx context.Context, span trace.Span, method, route string, elapsed time.Duration) {
+	if slowRequestThreshold <= 0 || elapsed <= slowRequestThreshold {
+		return
+	}
+	span.AddEvent("slow_request", trace.WithAttributes(
+		attribute.Int64("slow_request.threshold_ms", slowRequestThreshold.Milliseconds()),
+		attribute.Float64("slow_request.duration_ms", float64(elapsed.Microseconds())/1000),
+	))
+	slowRequests.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("http.request.method", method),
+		attribute.String("http.route", route),
+	))
+}
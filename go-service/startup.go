@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startup traces initialization as a "startup" root span with a child span
+// per phase. main moves through the phases in order; those that run before the
+// tracer provider exists are timed and turned into spans once it does.
+var startup = &startupTrace{start: time.Now()}
+
+type startupPhase struct {
+	name  string
+	start time.Time
+	end   time.Time
+	err   error
+}
+
+type startupTrace struct {
+	start   time.Time
+	current *startupPhase
+	pending []startupPhase
+	tp      *sdktrace.TracerProvider
+	ctx     context.Context
+	root    trace.Span
+}
+
+// begin ends the phase in progress, if any, and starts the named one
+func (s *startupTrace) begin(name string) {
+	now := time.Now()
+	s.endPhase(now)
+	s.current = &startupPhase{name: name, start: now}
+}
+
+// endPhase closes the current phase as a span, or keeps it for later if the
+// tracer provider does not exist yet
+func (s *startupTrace) endPhase(now time.Time) {
+	if s.current == nil {
+		return
+	}
+	phase := *s.current
+	phase.end = now
+	s.current = nil
+	if s.root == nil {
+		s.pending = append(s.pending, phase)
+		return
+	}
+	s.emit(phase)
+}
+
+func (s *startupTrace) emit(phase startupPhase) {
+	_, span := s.tp.Tracer("go-service").Start(s.ctx, "startup."+phase.name,
+		trace.WithTimestamp(phase.start),
+		trace.WithAttributes(attribute.String("startup.phase", phase.name)),
+	)
+	if phase.err != nil {
+		span.RecordError(phase.err)
+		span.SetStatus(codes.Error, phase.err.Error())
+	}
+	span.End(trace.WithTimestamp(phase.end))
+}
+
+// tracerReady starts the root span, backdated to process start, and emits
+// the phases completed so far beneath it
+func (s *startupTrace) tracerReady(tp *sdktrace.TracerProvider) {
+	s.tp = tp
+	s.ctx, s.root = tp.Tracer("go-service").Start(context.Background(), "startup",
+		trace.WithTimestamp(s.start),
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	for _, phase := range s.pending {
+		s.emit(phase)
+	}
+	s.pending = nil
+}
+
+// finish ends the last phase and the root span, failing both with err if set,
+// and flushes the spans so startup is visible even if the process then exits
+func (s *startupTrace) finish(err error) {
+	now := time.Now()
+	if s.current != nil {
+		s.current.err = err
+	}
+	s.endPhase(now)
+	if s.root == nil {
+		return
+	}
+	elapsed := now.Sub(s.start)
+	s.root.SetAttributes(attribute.Float64("startup.duration_ms", float64(elapsed.Microseconds())/1000))
+	if err != nil {
+		s.root.RecordError(err)
+		s.root.SetStatus(codes.Error, err.Error())
+	}
+	s.root.End(trace.WithTimestamp(now))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.tp.ForceFlush(ctx); err != nil {
+		log.Printf("Failed to flush startup spans: %v", err)
+	}
+	if err == nil {
+		log.Printf("Startup completed in %s", elapsed.Round(time.Millisecond))
+	}
+}
+
+// startupFatalf records a startup failure on the phase in progress, exports
+// the startup trace if it can, and exits like log.Fatalf
+func startupFatalf(format string, args ...any) {
+	err := fmt.Errorf(format, args...)
+	startup.finish(err)
+	log.Fatal(err)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// maxStreamEvents caps the number of events per /api/stream request
+	maxStreamEvents = 100
+	// maxStreamInterval caps the delay between events
+	maxStreamInterval = 5 * time.Second
+)
+
+type StreamEvent struct {
+	Index     int    `json:"index"`
+	Service   string `json:"service"`
+	Timestamp string `json:"timestamp"`
+}
+
+// streamHandler emits ?events= server-sent events (default 10) spaced
+// ?interval_ms= apart (default 500), recording a span event per flushed chunk
+func streamHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := tracer.Start(ctx, "stream-events")
+	defer span.End()
+
+	events := 10
+	if value := r.URL.Query().Get("events"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > maxStreamEvents {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("events must be an integer between 1 and %d", maxStreamEvents))
+			return
+		}
+		events = parsed
+	}
+
+	interval := 500 * time.Millisecond
+	if value := r.URL.Query().Get("interval_ms"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 || time.Duration(parsed)*time.Millisecond > maxStreamInterval {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("interval_ms must be an integer between 0 and %d", maxStreamInterval.Milliseconds()))
+			return
+		}
+		interval = time.Duration(parsed) * time.Millisecond
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		span.SetStatus(codes.Error, "response writer does not support flushing")
+		writeError(w, http.StatusInternalServerError, "Streaming not supported")
+		return
+	}
+
+	// A stream may outlive the server's read/write timeouts, so lift both
+	// deadlines (an expired read deadline would otherwise cancel ctx)
+	controller := http.NewResponseController(w)
+	if err := errors.Join(controller.SetReadDeadline(time.Time{}), controller.SetWriteDeadline(time.Time{})); err != nil {
+		span.AddEvent("deadlines not cleared", trace.WithAttributes(attribute.String("error.message", err.Error())))
+	}
+
+	span.SetAttributes(
+		attribute.Int("stream.events.requested", events),
+		attribute.Int64("stream.interval_ms", interval.Milliseconds()),
+	)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	start := time.Now()
+	sent := 0
+	for i := 0; i < events; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				// Client went away mid-stream
+				span.RecordError(ctx.Err())
+				span.SetStatus(codes.Error, "client disconnected")
+				span.SetAttributes(attribute.Int("stream.events.sent", sent))
+				return
+			}
+		}
+
+		data, _ := json.Marshal(StreamEvent{
+			Index:     i,
+			Service:   serviceName,
+			Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+		})
+		n, err := fmt.Fprintf(w, "id: %d\nevent: tick\ndata: %s\n\n", i, data)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			break
+		}
+		flusher.Flush()
+		sent++
+
+		span.AddEvent("sse.chunk", trace.WithAttributes(
+			attribute.Int("stream.event.index", i),
+			attribute.Int("stream.chunk.bytes", n),
+			attribute.Int64("stream.elapsed_ms", time.Since(start).Milliseconds()),
+		))
+	}
+
+	span.SetAttributes(attribute.Int("stream.events.sent", sent))
+	logger.InfoContext(ctx, "Stream completed", "events", sent, "duration_ms", time.Since(start).Milliseconds())
+}
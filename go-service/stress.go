@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxBurnDuration caps /api/burn so a single request cannot pin a CPU indefinitely
+const maxBurnDuration = 10 * time.Second
+
+// maxLeakMB caps a single /api/leak allocation
+const maxLeakMB = 1024
+
+// leaked holds allocations retained by /api/leak?hold=true
+var leaked struct {
+	sync.Mutex
+	buffers [][]byte
+	bytes   int64
+}
+
+type LeakResponse struct {
+	Service       string `json:"service"`
+	Timestamp     string `json:"timestamp"`
+	AllocatedMB   int    `json:"allocatedMb"`
+	Held          bool   `json:"held"`
+	RetainedBytes int64  `json:"retainedBytes"`
+}
+
+type BurnResponse struct {
+	Service    string `json:"service"`
+	Timestamp  string `json:"timestamp"`
+	BurnTimeMs int64  `json:"burnTimeMs"`
+	Iterations int64  `json:"iterations"`
+}
+
+// burnCPU hashes in a tight loop for d inside a "cpu-burn" child span and
+// returns the number of hash iterations performed
+func burnCPU(ctx context.Context, d time.Duration) int64 {
+	_, span := tracer.Start(ctx, "cpu-burn",
+		trace.WithAttributes(attribute.Int64("burn.requested_ms", d.Milliseconds())),
+	)
+	defer span.End()
+
+	var iterations int64
+	sum := sha256.Sum256([]byte("go-service"))
+	deadline := time.Now().Add(d)
+	for time.Now().Before(deadline) {
+		if ctx.Err() != nil {
+			recordCancellation(ctx, span, "cpu-burn")
+			break
+		}
+		for i := 0; i < 1000; i++ {
+			sum = sha256.Sum256(sum[:])
+		}
+		iterations += 1000
+	}
+
+	span.SetAttributes(attribute.Int64("burn.iterations", iterations))
+	return iterations
+}
+
+// burnHandler spins real CPU for ?ms= milliseconds (default 500)
+func burnHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := tracer.Start(ctx, "burn-request")
+	defer span.End()
+
+	ms := 500
+	if value := r.URL.Query().Get("ms"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeError(w, http.StatusBadRequest, "ms must be a positive integer")
+			return
+		}
+		ms = parsed
+	}
+	d := min(time.Duration(ms)*time.Millisecond, maxBurnDuration)
+
+	start := time.Now()
+	iterations := burnCPU(ctx, d)
+	if ctx.Err() != nil {
+		// The client is gone; there is nobody to respond to
+		return
+	}
+
+	response := BurnResponse{
+		Service:    serviceName,
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+		BurnTimeMs: time.Since(start).Milliseconds(),
+		Iterations: iterations,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// initStressMetrics registers the observable gauge reporting memory retained by /api/leak
+func initStressMetrics() error {
+	_, err := meter.Int64ObservableGauge(
+		"memory.leak.retained",
+		metric.WithDescription("Bytes currently retained by the /api/leak endpoint"),
+		metric.WithUnit("By"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			leaked.Lock()
+			defer leaked.Unlock()
+			o.Observe(leaked.bytes)
+			return nil
+		}),
+	)
+	return err
+}
+
+// leakHandler allocates ?mb= megabytes (default 50) and retains them when
+// ?hold=true; DELETE releases everything retained so far
+func leakHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := tracer.Start(ctx, "leak-request")
+	defer span.End()
+
+	if r.Method == http.MethodDelete {
+		leaked.Lock()
+		released := leaked.bytes
+		leaked.buffers = nil
+		leaked.bytes = 0
+		leaked.Unlock()
+
+		span.SetAttributes(attribute.Int64("memory.released_bytes", released))
+		logger.InfoContext(ctx, "Released retained memory", "memory.released_bytes", released)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	mb := 50
+	if value := r.URL.Query().Get("mb"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > maxLeakMB {
+			writeError(w, http.StatusBadRequest, "mb must be an integer between 1 and 1024")
+			return
+		}
+		mb = parsed
+	}
+	hold := r.URL.Query().Get("hold") == "true"
+
+	// Touch every page so the allocation is actually resident
+	buffer := make([]byte, mb<<20)
+	for i := 0; i < len(buffer); i += 4096 {
+		buffer[i] = 1
+	}
+
+	leaked.Lock()
+	if hold {
+		leaked.buffers = append(leaked.buffers, buffer)
+		leaked.bytes += int64(len(buffer))
+	}
+	retained := leaked.bytes
+	leaked.Unlock()
+
+	span.SetAttributes(
+		attribute.Int("memory.allocated_mb", mb),
+		attribute.Bool("memory.held", hold),
+		attribute.Int64("memory.retained_bytes", retained),
+	)
+
+	response := LeakResponse{
+		Service:       serviceName,
+		Timestamp:     time.Now().UTC().Format(time.RFC3339),
+		AllocatedMB:   mb,
+		Held:          hold,
+		RetainedBytes: retained,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// errorBiasedSampler is the error_biased sampler: traces the ratio sampler
+// picks are sampled as usual, and the rest are still recorded (RecordOnly) so
+// tailSamplingProcessor can keep them once it knows how they ended. A sampled
+// parent is always followed.
+type errorBiasedSampler struct {
+	ratio sdktrace.Sampler
+}
+
+func newErrorBiasedSampler(ratio float64) errorBiasedSampler {
+	return errorBiasedSampler{ratio: sdktrace.TraceIDRatioBased(ratio)}
+}
+
+func (s errorBiasedSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	parent := trace.SpanContextFromContext(p.ParentContext)
+	if parent.IsSampled() {
+		return sdktrace.SamplingResult{Decision: sdktrace.RecordAndSample, Tracestate: parent.TraceState()}
+	}
+	// A local parent that was not recorded stays unrecorded
+	if parent.IsValid() && !parent.IsRemote() && !trace.SpanFromContext(p.ParentContext).IsRecording() {
+		return sdktrace.SamplingResult{Decision: sdktrace.Drop, Tracestate: parent.TraceState()}
+	}
+	if !parent.IsValid() {
+		if result := s.ratio.ShouldSample(p); result.Decision == sdktrace.RecordAndSample {
+			return result
+		}
+	}
+	return sdktrace.SamplingResult{Decision: sdktrace.RecordOnly, Tracestate: parent.TraceState()}
+}
+
+func (s errorBiasedSampler) Description() string {
+	return "ErrorBiased{" + s.ratio.Description() + "}"
+}
+
+// Tail decisions recorded on sampler.tail.decisions
+const (
+	tailKeptError   = "kept_error"
+	tailKeptLatency = "kept_latency"
+	tailDropped     = "dropped"
+	tailOverflow    = "overflow"
+	tailExpired     = "expired"
+)
+
+// pendingTrace holds the recorded but unsampled spans of one trace until its
+// local root span ends
+type pendingTrace struct {
+	started time.Time
+	spans   []sdktrace.ReadOnlySpan
+	errored bool
+}
+
+// tailSamplingProcessor sits in front of the batch span processors. Sampled
+// spans pass straight through; recorded-but-unsampled spans are buffered per
+// trace until the trace's local root ends, and are then exported if any of
+// them ended in error or the root took at least the latency threshold, and
+// discarded otherwise. Spans that end after their local root are discarded.
+type tailSamplingProcessor struct {
+	next      []sdktrace.SpanProcessor
+	threshold time.Duration
+	maxTraces int
+	maxSpans  int
+	decisions metric.Int64Counter
+
+	mu      sync.Mutex
+	pending map[trace.TraceID]*pendingTrace
+}
+
+var _ sdktrace.SpanProcessor = (*tailSamplingProcessor)(nil)
+
+// tailMaxAge is how long a trace whose local root never ends (or a span
+// ending after its root) may hold a buffer slot once the buffer is full
+const tailMaxAge = time.Minute
+
+// newTailSamplingProcessor keeps traces slower than SAMPLER_LATENCY_THRESHOLD
+// (default: SLOW_REQUEST_THRESHOLD, else 1s), buffering at most
+// SAMPLER_TAIL_MAX_TRACES traces (1000) of SAMPLER_TAIL_MAX_SPANS spans (512)
+func newTailSamplingProcessor(next ...sdktrace.SpanProcessor) (*tailSamplingProcessor, error) {
+	p := &tailSamplingProcessor{
+		next:      next,
+		threshold: getEnvDuration("SAMPLER_LATENCY_THRESHOLD", getEnvDuration("SLOW_REQUEST_THRESHOLD", time.Second)),
+		maxTraces: max(getEnvInt("SAMPLER_TAIL_MAX_TRACES", 1000), 1),
+		maxSpans:  max(getEnvInt("SAMPLER_TAIL_MAX_SPANS", 512), 1),
+		pending:   make(map[trace.TraceID]*pendingTrace),
+	}
+	// The meter provider does not exist yet; the global meter delegates to it
+	var err error
+	p.decisions, err = otel.Meter("go-service").Int64Counter(
+		"sampler.tail.decisions",
+		metric.WithDescription("Traces the error_biased sampler did not pick, by whether the tail decision kept them"),
+		metric.WithUnit("{trace}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Tail sampling: latency threshold %s, buffering up to %d traces of %d spans", p.threshold, p.maxTraces, p.maxSpans)
+	return p, nil
+}
+
+func (p *tailSamplingProcessor) OnStart(ctx context.Context, s sdktrace.ReadWriteSpan) {
+	for _, next := range p.next {
+		next.OnStart(ctx, s)
+	}
+}
+
+func (p *tailSamplingProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if s.SpanContext().IsSampled() {
+		p.export(s)
+		return
+	}
+
+	localRoot := !s.Parent().IsValid() || s.Parent().IsRemote()
+	traceID := s.SpanContext().TraceID()
+
+	p.mu.Lock()
+	pt, ok := p.pending[traceID]
+	if !ok {
+		if len(p.pending) >= p.maxTraces {
+			p.evictStale()
+		}
+		if len(p.pending) >= p.maxTraces {
+			p.mu.Unlock()
+			p.record(tailOverflow)
+			return
+		}
+		pt = &pendingTrace{started: time.Now()}
+		p.pending[traceID] = pt
+	}
+	if len(pt.spans) < p.maxSpans {
+		pt.spans = append(pt.spans, s)
+	}
+	pt.errored = pt.errored || s.Status().Code == codes.Error
+	if !localRoot {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.pending, traceID)
+	p.mu.Unlock()
+
+	reason := ""
+	switch {
+	case pt.errored:
+		reason = tailKeptError
+	case p.threshold > 0 && s.EndTime().Sub(s.StartTime()) >= p.threshold:
+		reason = tailKeptLatency
+	}
+	if reason == "" {
+		p.record(tailDropped)
+		return
+	}
+	p.record(reason)
+	for _, span := range pt.spans {
+		p.export(tailSampledSpan{ReadOnlySpan: span, reason: reason})
+	}
+}
+
+// evictStale drops traces buffered for longer than tailMaxAge; mu must be held
+func (p *tailSamplingProcessor) evictStale() {
+	for traceID, pt := range p.pending {
+		if time.Since(pt.started) > tailMaxAge {
+			delete(p.pending, traceID)
+			p.record(tailExpired)
+		}
+	}
+}
+
+func (p *tailSamplingProcessor) record(decision string) {
+	p.decisions.Add(context.Background(), 1, metric.WithAttributes(attribute.String("sampling.decision", decision)))
+}
+
+func (p *tailSamplingProcessor) export(s sdktrace.ReadOnlySpan) {
+	for _, next := range p.next {
+		next.OnEnd(s)
+	}
+}
+
+func (p *tailSamplingProcessor) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, next := range p.next {
+		if err := next.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *tailSamplingProcessor) ForceFlush(ctx context.Context) error {
+	var errs []error
+	for _, next := range p.next {
+		if err := next.ForceFlush(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// tailSampledSpan presents a span kept by the tail decision as sampled, so the
+// batch span processors export it, and records why it was kept
+type tailSampledSpan struct {
+	sdktrace.ReadOnlySpan
+	reason string
+}
+
+func (s tailSampledSpan) SpanContext() trace.SpanContext {
+	sc := s.ReadOnlySpan.SpanContext()
+	return sc.WithTraceFlags(sc.TraceFlags().WithSampled(true))
+}
+
+func (s tailSampledSpan) Attributes() []attribute.KeyValue {
+	return append(slices.Clip(s.ReadOnlySpan.Attributes()), attribute.String("sampling.tail_reason", s.reason))
+}
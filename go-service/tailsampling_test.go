@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTailSamplingProcessor(t *testing.T) {
+	tests := []struct {
+		name       string
+		childError bool
+		rootTook   time.Duration
+		wantReason string
+	}{
+		{"fast trace is dropped", false, time.Millisecond, ""},
+		{"errored child keeps the trace", true, time.Millisecond, tailKeptError},
+		{"slow root keeps the trace", false, 2 * time.Second, tailKeptLatency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SAMPLER_LATENCY_THRESHOLD", "1s")
+			recorder := tracetest.NewSpanRecorder()
+			processor, err := newTailSamplingProcessor(recorder)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// A ratio of 0 leaves every trace to the tail decision
+			provider := sdktrace.NewTracerProvider(
+				sdktrace.WithSampler(newErrorBiasedSampler(0)),
+				sdktrace.WithSpanProcessor(processor),
+			)
+			t.Cleanup(func() { provider.Shutdown(context.Background()) })
+			tr := provider.Tracer("test")
+
+			start := time.Now()
+			ctx, root := tr.Start(context.Background(), "root", trace.WithTimestamp(start))
+			_, child := tr.Start(ctx, "child")
+			if tt.childError {
+				child.SetStatus(codes.Error, "failed")
+			}
+			child.End()
+			if got := len(recorder.Ended()); got != 0 {
+				t.Fatalf("%d spans exported before the local root ended, want 0", got)
+			}
+			root.End(trace.WithTimestamp(start.Add(tt.rootTook)))
+
+			ended := recorder.Ended()
+			if tt.wantReason == "" {
+				if len(ended) != 0 {
+					t.Errorf("%d spans exported, want the trace dropped", len(ended))
+				}
+				return
+			}
+			if len(ended) != 2 {
+				t.Fatalf("%d spans exported, want both spans of the trace", len(ended))
+			}
+			for _, span := range ended {
+				if !span.SpanContext().IsSampled() {
+					t.Errorf("span %q exported unsampled", span.Name())
+				}
+				want := attribute.String("sampling.tail_reason", tt.wantReason)
+				found := false
+				for _, attr := range span.Attributes() {
+					found = found || attr == want
+				}
+				if !found {
+					t.Errorf("span %q attributes %v, want %v", span.Name(), span.Attributes(), want)
+				}
+			}
+			if len(processor.pending) != 0 {
+				t.Errorf("%d traces still buffered after the local root ended", len(processor.pending))
+			}
+		})
+	}
+}
+
+func TestTailSamplingProcessorSampledPassThrough(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	processor, err := newTailSamplingProcessor(recorder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(newErrorBiasedSampler(1)),
+		sdktrace.WithSpanProcessor(processor),
+	)
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	ctx, root := provider.Tracer("test").Start(context.Background(), "root")
+	_, child := provider.Tracer("test").Start(ctx, "child")
+	child.End()
+	if got := len(recorder.Ended()); got != 1 {
+		t.Errorf("%d spans exported before the root ended, want the sampled child passed straight through", got)
+	}
+	root.End()
+	if len(processor.pending) != 0 {
+		t.Error("sampled spans were buffered")
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	// tenantHeader carries the caller's tenant identifier
+	tenantHeader = "X-Tenant-Id"
+	// tenantUnknown is recorded for requests without a tenant header
+	tenantUnknown = "_unknown"
+)
+
+// otherValue replaces attribute values beyond a boundedValues limit
+const otherValue = "_other"
+
+// boundedValues caps the cardinality of a metric attribute: the first max
+// distinct values seen are recorded as-is and the rest collapse into otherValue
+type boundedValues struct {
+	mu   sync.Mutex
+	seen map[string]struct{}
+	max  int
+}
+
+func newBoundedValues(max int) *boundedValues {
+	return &boundedValues{seen: make(map[string]struct{}), max: max}
+}
+
+// value returns v if it is (or can become) one of the tracked values, and
+// otherValue once the limit has been reached
+func (b *boundedValues) value(v string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.seen[v]; ok {
+		return v
+	}
+	if len(b.seen) >= b.max {
+		return otherValue
+	}
+	b.seen[v] = struct{}{}
+	return v
+}
+
+// tenantTracker records per-tenant request metrics with a bounded tenant.id
+// attribute. Spans always carry the raw tenant ID.
+type tenantTracker struct {
+	ids *boundedValues
+
+	requests metric.Int64Counter
+	errors   metric.Int64Counter
+}
+
+var tenants *tenantTracker
+
+// initTenantMetrics creates the per-tenant counters, keeping at most
+// TENANT_MAX_CARDINALITY (default 20) distinct tenant.id values
+func initTenantMetrics() error {
+	t := &tenantTracker{
+		ids: newBoundedValues(max(getEnvInt("TENANT_MAX_CARDINALITY", 20), 1)),
+	}
+
+	var err error
+	t.requests, err = meter.Int64Counter(
+		"tenant.requests",
+		metric.WithDescription("The number of HTTP requests per tenant"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return err
+	}
+
+	t.errors, err = meter.Int64Counter(
+		"tenant.errors",
+		metric.WithDescription("The number of HTTP requests per tenant that returned a 5xx status"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return err
+	}
+
+	tenants = t
+	return nil
+}
+
+// tenantID returns the request's tenant from the X-Tenant-Id header, or "" if absent
+func tenantID(r *http.Request) string {
+	return strings.TrimSpace(r.Header.Get(tenantHeader))
+}
+
+// metricValue maps a tenant ID onto the bounded set used as a metric attribute
+func (t *tenantTracker) metricValue(tenant string) string {
+	if tenant == "" {
+		return tenantUnknown
+	}
+	return t.ids.value(tenant)
+}
+
+// Record counts a completed request (and any server error) against its tenant
+func (t *tenantTracker) Record(ctx context.Context, tenant string, status int) {
+	attrs := metric.WithAttributes(attribute.String("tenant.id", t.metricValue(tenant)))
+	t.requests.Add(ctx, 1, attrs)
+	if status >= http.StatusInternalServerError {
+		t.errors.Add(ctx, 1, attrs)
+	}
+}
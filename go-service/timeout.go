@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// defaultHandlerTimeoutRoutes exempts the long-lived streaming routes; setting
+// HANDLER_TIMEOUT_ROUTES (even to "") replaces it
+const defaultHandlerTimeoutRoutes = "/api/stream=0,/ws=0"
+
+// errHandlerTimeout is the cancellation cause of a handler that outlived its
+// route's timeout, distinguishing it from the client's own deadline
+var errHandlerTimeout = errors.New("handler timeout exceeded")
+
+// Handler timeouts from HANDLER_TIMEOUT, overridden per route by
+// HANDLER_TIMEOUT_ROUTES; 0 disables the timeout
+var (
+	handlerTimeout       time.Duration
+	handlerTimeoutRoutes map[string]time.Duration
+)
+
+var handlerTimeouts metric.Int64Counter
+
+func initHandlerTimeouts() error {
+	handlerTimeout = getEnvDuration("HANDLER_TIMEOUT", 30*time.Second)
+	handlerTimeoutRoutes = make(map[string]time.Duration)
+	value, ok := os.LookupEnv("HANDLER_TIMEOUT_ROUTES")
+	if !ok {
+		value = defaultHandlerTimeoutRoutes
+	}
+	for _, pair := range splitList(value) {
+		route, raw, ok := strings.Cut(pair, "=")
+		d, err := time.ParseDuration(strings.TrimSpace(raw))
+		if !ok || err != nil || d < 0 {
+			log.Printf("Ignoring invalid HANDLER_TIMEOUT_ROUTES entry %q; want route=duration", pair)
+			continue
+		}
+		handlerTimeoutRoutes[strings.TrimSpace(route)] = d
+	}
+
+	var err error
+	handlerTimeouts, err = meter.Int64Counter(
+		"http.server.handler_timeouts",
+		metric.WithDescription("The number of HTTP requests cut off with 503 because the handler outlived its route's timeout"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return err
+	}
+	log.Printf("Handler timeout: %s, per route: %v", handlerTimeout, handlerTimeoutRoutes)
+	return nil
+}
+
+// withHandlerTimeout bounds the handler's work by its route's timeout; the
+// earlier of this and any client deadline applies
+func withHandlerTimeout(ctx context.Context, route string) (context.Context, context.CancelFunc) {
+	timeout, ok := handlerTimeoutRoutes[route]
+	if !ok {
+		timeout = handlerTimeout
+	}
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, timeout, errHandlerTimeout)
+}
+
+// handlerTimedOut reports whether the handler's context was cancelled by its
+// route's timeout rather than the client
+func handlerTimedOut(parent, handler context.Context) bool {
+	return parent.Err() == nil && errors.Is(context.Cause(handler), errHandlerTimeout)
+}
+
+// recordHandlerTimeout marks a request cut off by its route's timeout
+func recordHandlerTimeout(ctx context.Context, span trace.Span, method, route string) {
+	span.SetAttributes(attribute.Bool("http.server.handler_timeout", true))
+	handlerTimeouts.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("http.request.method", method),
+		attribute.String("http.route", route),
+	))
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// unixSocketPath is where the API server also listens, alongside TCP, when
+// UNIX_SOCKET_PATH is set, so a sidecar can reach it without a port
+func unixSocketPath() string {
+	path := os.Getenv("UNIX_SOCKET_PATH")
+	if path == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return path
+}
+
+// unixSocketAttributes identify the socket on the resource, using the network
+// attributes semantic conventions apply to unix domain sockets
+func unixSocketAttributes() []attribute.KeyValue {
+	path := unixSocketPath()
+	if path == "" {
+		return nil
+	}
+	return []attribute.KeyValue{
+		attribute.String("network.transport", "unix"),
+		attribute.String("network.local.address", path),
+	}
+}
+
+// listenUnix binds the unix socket at path, replacing a socket left behind by
+// an earlier run (but never any other kind of file), and applies
+// UNIX_SOCKET_MODE, an octal permission such as 0660, when set
+func listenUnix(path string) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if mode := os.Getenv("UNIX_SOCKET_MODE"); mode != "" {
+		perm, err := strconv.ParseUint(mode, 8, 32)
+		if err == nil {
+			err = os.Chmod(path, fs.FileMode(perm))
+		}
+		if err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("applying UNIX_SOCKET_MODE %q: %w", mode, err)
+		}
+	}
+	log.Printf("Also listening on unix socket %s", path)
+	return listener, nil
+}
+
+type connTransportKey struct{}
+
+// withConnTransport is the server's ConnContext hook; it remembers which
+// transport each connection arrived over
+func withConnTransport(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, connTransportKey{}, c.LocalAddr().Network())
+}
+
+// unixSocketRequest reports whether the request arrived over the unix socket
+func unixSocketRequest(ctx context.Context) bool {
+	network, _ := ctx.Value(connTransportKey{}).(string)
+	return network == "unix"
+}
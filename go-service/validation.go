@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxRequestBodyBytes caps JSON request bodies
+const maxRequestBodyBytes = 1 << 20
+
+// validation.reason values describing why a request body was rejected
+const (
+	reasonMalformed    = "malformed_json"
+	reasonUnknownField = "unknown_field"
+	reasonWrongType    = "wrong_type"
+	reasonRequired     = "required"
+	reasonOutOfRange   = "out_of_range"
+	reasonTooLarge     = "too_large"
+	reasonBadEncoding  = "invalid_encoding"
+)
+
+// FieldError is one problem with a request body; Field is the JSON path of
+// the offending field, or empty when the body as a whole is invalid
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// ValidationProblem is the problem+json body of a 400, listing every invalid
+// field as the fields extension member
+type ValidationProblem struct {
+	ProblemDetails
+	Fields []FieldError `json:"fields"`
+}
+
+// validatable is a request body that can check its own field values
+type validatable interface {
+	validate() []FieldError
+}
+
+var validationFailures metric.Int64Counter
+
+func initValidationMetrics() error {
+	var err error
+	validationFailures, err = meter.Int64Counter(
+		"http.server.validation_failures",
+		metric.WithDescription("The number of request body validation failures, one per invalid field"),
+		metric.WithUnit("{failure}"),
+	)
+	return err
+}
+
+// decodeBody decodes r's JSON body into dst and validates it. On failure it
+// records the problems on ctx's span, responds 400 with the per-field errors,
+// and returns false.
+func decodeBody(ctx context.Context, w http.ResponseWriter, r *http.Request, dst validatable) bool {
+	fields := decodeJSON(w, r, dst)
+	if len(fields) == 0 {
+		fields = dst.validate()
+	}
+	if len(fields) == 0 {
+		return true
+	}
+	rejectInvalid(ctx, w, r, fields)
+	return false
+}
+
+// decodeJSON strictly decodes a single JSON value: unknown fields, trailing
+// data, and bodies over maxRequestBodyBytes are errors
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) []FieldError {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(dst)
+	if err == nil {
+		if decoder.Decode(&struct{}{}) != io.EOF {
+			return []FieldError{{Reason: reasonMalformed, Message: "body must contain a single JSON object"}}
+		}
+		return nil
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var sizeErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &sizeErr):
+		return []FieldError{{Reason: reasonTooLarge, Message: fmt.Sprintf("body must not exceed %d bytes", sizeErr.Limit)}}
+	case errors.Is(err, io.EOF):
+		return []FieldError{{Reason: reasonRequired, Message: "request body is required"}}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return []FieldError{{Reason: reasonMalformed, Message: "truncated JSON body"}}
+	case errors.As(err, &syntaxErr):
+		return []FieldError{{Reason: reasonMalformed, Message: fmt.Sprintf("invalid JSON at offset %d", syntaxErr.Offset)}}
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return []FieldError{{Reason: reasonWrongType, Message: "body must be a JSON object"}}
+		}
+		return []FieldError{{Field: typeErr.Field, Reason: reasonWrongType, Message: fmt.Sprintf("must be %s, not %s", jsonKind(typeErr.Type.Kind().String()), typeErr.Value)}}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return []FieldError{{Field: field, Reason: reasonUnknownField, Message: "unknown field"}}
+	}
+	return []FieldError{{Reason: reasonMalformed, Message: "invalid JSON body"}}
+}
+
+// jsonKind names a Go kind the way a JSON client would think of it
+func jsonKind(kind string) string {
+	switch {
+	case strings.HasPrefix(kind, "int"), strings.HasPrefix(kind, "uint"):
+		return "an integer"
+	case strings.HasPrefix(kind, "float"):
+		return "a number"
+	case kind == "string":
+		return "a string"
+	case kind == "bool":
+		return "a boolean"
+	case kind == "slice", kind == "array":
+		return "an array"
+	}
+	return "an object"
+}
+
+// rejectInvalid records each problem as a validation.failed span event and
+// counts it by route and reason, then responds 400 with the problems listed
+func rejectInvalid(ctx context.Context, w http.ResponseWriter, r *http.Request, fields []FieldError) {
+	span := trace.SpanFromContext(ctx)
+	for _, field := range fields {
+		span.AddEvent("validation.failed", trace.WithAttributes(
+			attribute.String("validation.field", field.Field),
+			attribute.String("validation.reason", field.Reason),
+			attribute.String("validation.message", field.Message),
+		))
+		validationFailures.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("http.route", httpRoute(r)),
+			attribute.String("validation.reason", field.Reason),
+		))
+	}
+	span.SetAttributes(attribute.Int("validation.failures", len(fields)))
+	setErrorType(ctx, span, errorTypeValidation)
+
+	problem := newProblem(w, http.StatusBadRequest, fmt.Sprintf("%d invalid field(s)", len(fields)))
+	problem.Type = problemTypeValidation
+	problem.Title = "Request validation failed"
+	writeProblem(w, http.StatusBadRequest, ValidationProblem{ProblemDetails: problem, Fields: fields})
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// defaultDurationBuckets favors sub-100ms latencies, where most requests to
+// this service land
+var defaultDurationBuckets = []float64{
+	0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 1, 2.5, 5, 10,
+}
+
+// durationBuckets parses DURATION_HISTOGRAM_BUCKETS (seconds, comma-separated,
+// ascending), falling back to defaultDurationBuckets
+func durationBuckets() []float64 {
+	value := os.Getenv("DURATION_HISTOGRAM_BUCKETS")
+	if value == "" {
+		return defaultDurationBuckets
+	}
+
+	var buckets []float64
+	for _, item := range splitList(value) {
+		bound, err := strconv.ParseFloat(item, 64)
+		if err != nil || (len(buckets) > 0 && bound <= buckets[len(buckets)-1]) {
+			log.Printf("Invalid DURATION_HISTOGRAM_BUCKETS %q, using defaults", value)
+			return defaultDurationBuckets
+		}
+		buckets = append(buckets, bound)
+	}
+	return buckets
+}
+
+// durationHistograms lists the duration instruments whose aggregation is configured by Views
+var durationHistograms = []string{"http.server.request.duration", "messaging.queue.latency"}
+
+// durationAggregation selects explicit-bucket or base2 exponential histograms
+// for duration instruments via OTEL_EXPORTER_OTLP_METRICS_DEFAULT_HISTOGRAM_AGGREGATION
+func durationAggregation() sdkmetric.Aggregation {
+	switch value := os.Getenv("OTEL_EXPORTER_OTLP_METRICS_DEFAULT_HISTOGRAM_AGGREGATION"); value {
+	case "base2_exponential_bucket_histogram":
+		return sdkmetric.AggregationBase2ExponentialHistogram{MaxSize: 160, MaxScale: 20}
+	case "", "explicit_bucket_histogram":
+		return sdkmetric.AggregationExplicitBucketHistogram{Boundaries: durationBuckets()}
+	default:
+		log.Printf("Unsupported histogram aggregation %q, using explicit_bucket_histogram", value)
+		return sdkmetric.AggregationExplicitBucketHistogram{Boundaries: durationBuckets()}
+	}
+}
+
+// metricViews builds the Views applied to the MeterProvider:
+//   - explicit bucket boundaries (or exponential buckets) for duration histograms
+//   - instruments listed in METRICS_DROP are dropped
+//   - METRICS_RENAME entries (old=new) rename instruments
+//
+// The SDK exports a separate stream for every View an instrument matches, so
+// the settings for each instrument are merged into a single View: a renamed
+// duration histogram keeps its buckets, and a dropped one is not exported at
+// all.
+func metricViews() []sdkmetric.View {
+	var names []string
+	streams := make(map[string]*sdkmetric.Stream)
+	stream := func(name string) *sdkmetric.Stream {
+		if streams[name] == nil {
+			names = append(names, name)
+			streams[name] = &sdkmetric.Stream{}
+		}
+		return streams[name]
+	}
+
+	aggregation := durationAggregation()
+	for _, name := range durationHistograms {
+		stream(name).Aggregation = aggregation
+	}
+
+	for _, rename := range splitList(os.Getenv("METRICS_RENAME")) {
+		from, to, ok := strings.Cut(rename, "=")
+		if !ok || from == "" || to == "" {
+			log.Printf("Ignoring invalid METRICS_RENAME entry %q", rename)
+			continue
+		}
+		stream(from).Name = to
+	}
+
+	for _, name := range splitList(os.Getenv("METRICS_DROP")) {
+		stream(name).Aggregation = sdkmetric.AggregationDrop{}
+	}
+
+	views := make([]sdkmetric.View, 0, len(names))
+	for _, name := range names {
+		views = append(views, sdkmetric.NewView(sdkmetric.Instrument{Name: name}, *streams[name]))
+	}
+	return views
+}
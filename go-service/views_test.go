@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestMetricViews(t *testing.T) {
+	tests := []struct {
+		name   string
+		drop   string
+		rename string
+		// want maps each exported stream to whether it should carry the
+		// configured duration buckets
+		want map[string]bool
+	}{
+		{"defaults", "", "", map[string]bool{
+			"http.server.request.duration": true, "messaging.queue.latency": true, "jobs.processed": false,
+		}},
+		{"drop duration histogram", "http.server.request.duration", "", map[string]bool{
+			"messaging.queue.latency": true, "jobs.processed": false,
+		}},
+		{"drop counter", "jobs.processed", "", map[string]bool{
+			"http.server.request.duration": true, "messaging.queue.latency": true,
+		}},
+		{"rename duration histogram keeps buckets", "", "http.server.request.duration=http.duration", map[string]bool{
+			"http.duration": true, "messaging.queue.latency": true, "jobs.processed": false,
+		}},
+		{"rename counter", "", "jobs.processed=jobs.done", map[string]bool{
+			"http.server.request.duration": true, "messaging.queue.latency": true, "jobs.done": false,
+		}},
+		{"drop wins over rename", "messaging.queue.latency", "messaging.queue.latency=queue.latency", map[string]bool{
+			"http.server.request.duration": true, "jobs.processed": false,
+		}},
+		{"invalid rename ignored", "", "jobs.processed", map[string]bool{
+			"http.server.request.duration": true, "messaging.queue.latency": true, "jobs.processed": false,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("METRICS_DROP", tt.drop)
+			t.Setenv("METRICS_RENAME", tt.rename)
+			t.Setenv("DURATION_HISTOGRAM_BUCKETS", "")
+			t.Setenv("OTEL_EXPORTER_OTLP_METRICS_DEFAULT_HISTOGRAM_AGGREGATION", "")
+
+			got := collectStreams(t, metricViews())
+			if len(got) != len(tt.want) {
+				t.Errorf("exported %v, want %v", keys(got), keys(tt.want))
+			}
+			for name, wantBuckets := range tt.want {
+				bounds, ok := got[name]
+				if !ok {
+					t.Errorf("stream %q was not exported; got %v", name, keys(got))
+					continue
+				}
+				if wantBuckets && !slices.Equal(bounds, defaultDurationBuckets) {
+					t.Errorf("stream %q has bounds %v, want the duration buckets", name, bounds)
+				}
+			}
+		})
+	}
+}
+
+// collectStreams records to the two duration histograms and a counter through
+// a MeterProvider with views, returning each exported stream's name and, for
+// histograms, its bucket bounds
+func collectStreams(t *testing.T, views []sdkmetric.View) map[string][]float64 {
+	t.Helper()
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader), sdkmetric.WithView(views...))
+	t.Cleanup(func() { provider.Shutdown(ctx) })
+
+	m := provider.Meter("test")
+	for _, name := range durationHistograms {
+		histogram, err := m.Float64Histogram(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		histogram.Record(ctx, 0.02)
+	}
+	counter, err := m.Int64Counter("jobs.processed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter.Add(ctx, 1)
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatal(err)
+	}
+	streams := make(map[string][]float64)
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			var bounds []float64
+			if histogram, ok := m.Data.(metricdata.Histogram[float64]); ok && len(histogram.DataPoints) > 0 {
+				bounds = histogram.DataPoints[0].Bounds
+			}
+			if _, dup := streams[m.Name]; dup {
+				t.Errorf("stream %q exported twice", m.Name)
+			}
+			streams[m.Name] = bounds
+		}
+	}
+	return streams
+}
+
+func keys[V any](m map[string]V) []string {
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/websocket"
+)
+
+// wsMetrics holds the WebSocket connection and message instruments
+type wsMetrics struct {
+	messages           metric.Int64Counter
+	messageSize        metric.Int64Histogram
+	activeConnections  metric.Int64UpDownCounter
+	connectionDuration metric.Float64Histogram
+}
+
+var ws wsMetrics
+
+func initWebSocketMetrics() error {
+	var err error
+	ws.messages, err = meter.Int64Counter(
+		"websocket.messages",
+		metric.WithDescription("The number of WebSocket messages, by direction"),
+		metric.WithUnit("{message}"),
+	)
+	if err != nil {
+		return err
+	}
+
+	ws.messageSize, err = meter.Int64Histogram(
+		"websocket.message.size",
+		metric.WithDescription("Size of WebSocket message payloads"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return err
+	}
+
+	ws.activeConnections, err = meter.Int64UpDownCounter(
+		"websocket.active_connections",
+		metric.WithDescription("Number of open WebSocket connections"),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return err
+	}
+
+	ws.connectionDuration, err = meter.Float64Histogram(
+		"websocket.connection.duration",
+		metric.WithDescription("Lifetime of WebSocket connections"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(0.1, 0.5, 1, 5, 10, 30, 60, 300, 600, 1800, 3600),
+	)
+	return err
+}
+
+// wsEcho echoes every message back to the client, one span per message. The
+// request's server span stays open for the lifetime of the connection.
+func wsEcho(conn *websocket.Conn) {
+	ctx := conn.Request().Context()
+	start := time.Now()
+
+	// The hijacked connection inherits the server's read/write deadlines;
+	// clear them so long-lived sockets are not cut off
+	conn.SetDeadline(time.Time{})
+
+	ws.activeConnections.Add(ctx, 1)
+	defer ws.activeConnections.Add(ctx, -1)
+
+	count := 0
+	defer func() {
+		ws.connectionDuration.Record(ctx, time.Since(start).Seconds())
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Int("websocket.messages.received", count))
+		logger.InfoContext(ctx, "WebSocket connection closed", "messages", count, "duration_ms", time.Since(start).Milliseconds())
+	}()
+
+	for {
+		var msg []byte
+		if err := websocket.Message.Receive(conn, &msg); err != nil {
+			if !errors.Is(err, io.EOF) {
+				trace.SpanFromContext(ctx).RecordError(err)
+			}
+			return
+		}
+		count++
+
+		_, span := tracer.Start(ctx, "websocket.message",
+			trace.WithAttributes(
+				attribute.Int("websocket.message.index", count),
+				attribute.Int("websocket.message.size", len(msg)),
+			),
+		)
+		ws.messages.Add(ctx, 1, metric.WithAttributes(attribute.String("websocket.direction", "received")))
+		ws.messageSize.Record(ctx, int64(len(msg)), metric.WithAttributes(attribute.String("websocket.direction", "received")))
+
+		if err := websocket.Message.Send(conn, msg); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			span.End()
+			return
+		}
+		ws.messages.Add(ctx, 1, metric.WithAttributes(attribute.String("websocket.direction", "sent")))
+		ws.messageSize.Record(ctx, int64(len(msg)), metric.WithAttributes(attribute.String("websocket.direction", "sent")))
+		span.End()
+	}
+}
+
+// wsHandler upgrades /ws to a WebSocket echo connection. Server (rather than
+// websocket.Handler) skips the Origin check so load tools can connect freely.
+func wsHandler(w http.ResponseWriter, r *http.Request) {
+	websocket.Server{Handler: wsEcho}.ServeHTTP(w, r)
+}